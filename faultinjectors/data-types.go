@@ -1,5 +1,9 @@
 package faultinjectors
 
+import (
+	"encoding/json"
+)
+
 // Structs.
 
 // CrashFailure
@@ -77,32 +81,318 @@ type Missing struct {
 	Goals []*Goal
 }
 
+// MessageDiff describes a message of a successful run
+// that a failed run dropped or delivered late, along
+// with the fault explaining it, if any. Necessary marks
+// messages the successful run's consequent depends on.
+type MessageDiff struct {
+	Message   *Message      `json:"message"`
+	Status    string        `json:"status"`
+	Delay     uint          `json:"delay,omitempty"`
+	Omission  *MessageLoss  `json:"omission,omitempty"`
+	Crash     *CrashFailure `json:"crash,omitempty"`
+	Necessary bool          `json:"necessary"`
+}
+
+// RootCauseScores breaks down the score of a root-cause
+// candidate into its weighted signals: its depth below the
+// consequent, whether a fault explains it, whether it is
+// a network event, and how many failed runs miss it.
+type RootCauseScores struct {
+	Distance  float64 `json:"distance"`
+	Fault     float64 `json:"fault"`
+	Async     float64 `json:"async"`
+	Frequency float64 `json:"frequency"`
+}
+
+// RootCause is a missing event of a failed run ranked as
+// candidate root cause. Score sums the signals in Scores.
+// Distance counts the steps from the consequent to Rule,
+// -1 if Rule does not lie below it. MissingIn counts the
+// failed runs missing the event, Crashes and Omissions
+// list the faults that hit its nodes or channel.
+type RootCause struct {
+	Rank      int              `json:"rank"`
+	Rule      *Rule            `json:"rule"`
+	Goals     []*Goal          `json:"goals"`
+	Score     float64          `json:"score"`
+	Scores    *RootCauseScores `json:"scores"`
+	Distance  int              `json:"distance"`
+	MissingIn int              `json:"missingIn"`
+	Crashes   []CrashFailure   `json:"crashes,omitempty"`
+	Omissions []MessageLoss    `json:"omissions,omitempty"`
+}
+
+// FailureSignature characterizes how a run failed: which
+// events it misses, modulo time, which rules of the success
+// prototypes it misses, and how many faults were injected.
+type FailureSignature struct {
+	MissingEvents     []string `json:"missingEvents"`
+	InterProtoMissing []string `json:"interProtoMissing"`
+	UnionProtoMissing []string `json:"unionProtoMissing"`
+	Crashes           int      `json:"crashes"`
+	Omissions         int      `json:"omissions"`
+}
+
+// FailureCluster groups the failed runs sharing one
+// failure signature. Representative is its earliest member.
+type FailureCluster struct {
+	ID             int               `json:"id"`
+	Representative uint              `json:"representative"`
+	Members        []uint            `json:"members"`
+	Signature      *FailureSignature `json:"signature"`
+}
+
+// Suspiciousness scores how strongly a rule table or a
+// node is associated with failure, based on how many
+// failed and successful runs fire the rule or derive
+// goals at the node. Ochiai and Tarantula range from 0
+// (never in a failed run) to 1.
+type Suspiciousness struct {
+	Element    string  `json:"element"`
+	Kind       string  `json:"kind"`
+	Ochiai     float64 `json:"ochiai"`
+	Tarantula  float64 `json:"tarantula"`
+	FailedRuns int     `json:"failedRuns"`
+	PassedRuns int     `json:"passedRuns"`
+}
+
+// RunPairing names the successful run a failed run is
+// compared against in differential analyses, chosen as
+// the one with the most similar consequent provenance.
+// Similarity is the Jaccard index of both runs' sets of
+// goal and rule labels, ranging from 0 to 1.
+type RunPairing struct {
+	SuccessRun uint    `json:"successRun"`
+	Similarity float64 `json:"similarity"`
+}
+
+// ShiftedGoal is a goal of a successful run that
+// a failed run derived Shift time steps later (or
+// earlier, if negative), as FailedLabel.
+type ShiftedGoal struct {
+	Label       string `json:"label"`
+	FailedLabel string `json:"failedLabel"`
+	Shift       int    `json:"shift"`
+}
+
+// ProvAlignment counts the goals and rules of a successful
+// run's consequent provenance by how they occur in a failed
+// run: identically (matched), at a different time (shifted),
+// or not at all (missing).
+type ProvAlignment struct {
+	Matched      int            `json:"matched"`
+	Shifted      int            `json:"shifted"`
+	Missing      int            `json:"missing"`
+	ShiftedGoals []*ShiftedGoal `json:"shiftedGoals,omitempty"`
+}
+
+// HazardWindow is an interval of time steps, Start to End
+// inclusively, in which the antecedent held at Node while
+// the consequent did not yet. Closed tells whether the
+// consequent held at Node eventually. Crashes and omissions
+// are the faults that hit Node before the window ended.
+type HazardWindow struct {
+	Node      string         `json:"node"`
+	Start     uint           `json:"start"`
+	End       uint           `json:"end"`
+	Length    uint           `json:"length"`
+	Closed    bool           `json:"closed"`
+	Crashes   []CrashFailure `json:"crashes,omitempty"`
+	Omissions []MessageLoss  `json:"omissions,omitempty"`
+}
+
 // Run
 type Run struct {
-	Iteration         uint            `json:"iteration"`
-	Status            string          `json:"status"`
-	FailureSpec       *FailureSpec    `json:"failureSpec"`
-	Model             *Model          `json:"model"`
-	Messages          []*Message      `json:"messages"`
-	PreProv           *ProvData       `json:"preProv,omitempty"`
-	TimePreHolds      map[string]bool `json:"timePreHolds,omitempty"`
-	PostProv          *ProvData       `json:"postProv,omitempty"`
-	TimePostHolds     map[string]bool `json:"timePostHolds,omitempty"`
-	Recommendation    []string        `json:"recommendation,omitempty"`
-	Corrections       []string        `json:"corrections,omitempty"`
-	MissingEvents     []*Missing      `json:"missingEvents,omitempty"`
-	InterProto        []string        `json:"interProto,omitempty"`
-	InterProtoMissing []string        `json:"interProtoMissing,omitempty"`
-	UnionProto        []string        `json:"unionProto,omitempty"`
-	UnionProtoMissing []string        `json:"unionProtoMissing,omitempty"`
-}
-
-// Molly
-type Molly struct {
-	Run              string
-	OutputDir        string
+	Iteration         uint                       `json:"iteration"`
+	Status            string                     `json:"status"`
+	FailureSpec       *FailureSpec               `json:"failureSpec"`
+	Model             *Model                     `json:"model"`
+	Messages          []*Message                 `json:"messages"`
+	PreProv           *ProvData                  `json:"preProv,omitempty"`
+	TimePreHolds      map[string]bool            `json:"timePreHolds,omitempty"`
+	PostProv          *ProvData                  `json:"postProv,omitempty"`
+	TimePostHolds     map[string]bool            `json:"timePostHolds,omitempty"`
+	NodesPreHolds     map[string]map[string]bool `json:"nodesPreHolds,omitempty"`
+	NodesPostHolds    map[string]map[string]bool `json:"nodesPostHolds,omitempty"`
+	HazardWindows     []*HazardWindow            `json:"hazardWindows,omitempty"`
+	Recommendation    []string                   `json:"recommendation,omitempty"`
+	Corrections       []string                   `json:"corrections,omitempty"`
+	Pairing           *RunPairing                `json:"pairing,omitempty"`
+	Alignment         *ProvAlignment             `json:"alignment,omitempty"`
+	MissingEvents     []*Missing                 `json:"missingEvents,omitempty"`
+	ExcessEvents      []*Missing                 `json:"excessEvents,omitempty"`
+	RootCauses        []*RootCause               `json:"rootCauses,omitempty"`
+	FailureCluster    *FailureCluster            `json:"failureCluster,omitempty"`
+	MessageDiff       []*MessageDiff             `json:"messageDiff,omitempty"`
+	InterProto        []string                   `json:"interProto,omitempty"`
+	InterProtoMissing []string                   `json:"interProtoMissing,omitempty"`
+	UnionProto        []string                   `json:"unionProto,omitempty"`
+	UnionProtoMissing []string                   `json:"unionProtoMissing,omitempty"`
+	Suspiciousness    []*Suspiciousness          `json:"suspiciousness,omitempty"`
+}
+
+// Problem is one inconsistency found while
+// validating fault injector output, located by
+// file and JSON path.
+type Problem struct {
+	File    string `json:"file"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Problems collects all inconsistencies found in
+// fault injector output.
+type Problems []*Problem
+
+// Output holds the runs any fault injector
+// produced, in the form Nemo analyzes them.
+type Output struct {
 	Runs             []*Run
 	RunsIters        []uint
 	SuccessRunsIters []uint
 	FailedRunsIters  []uint
+	Fingerprint      string
+}
+
+// NemoHeader identifies a document in the
+// Nemo-native input format and its version.
+type NemoHeader struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+// NemoDocument is the JSON flavour of the
+// Nemo-native input format: header and all
+// runs in one object.
+type NemoDocument struct {
+	NemoHeader
+	Runs []*Run `json:"runs"`
+}
+
+// Nemo reads fault injection runs from the
+// Nemo-native input format.
+type Nemo struct {
+	Run       string
+	InputPath string
+	Output
+}
+
+// JepsenOp is one event of a Jepsen history:
+// the invocation or completion of a client or
+// nemesis operation.
+type JepsenOp struct {
+	Index   int64
+	Type    string
+	F       string
+	Value   interface{}
+	Process string
+	Time    int64
+}
+
+// Jepsen reads Jepsen (or Elle) test histories,
+// one test per run.
+type Jepsen struct {
+	Run       string
+	OutputDir string
+	Output
+}
+
+// OTLPAnyValue is an attribute value of an
+// OTLP JSON export.
+type OTLPAnyValue struct {
+	StringValue *string      `json:"stringValue,omitempty"`
+	BoolValue   *bool        `json:"boolValue,omitempty"`
+	IntValue    *json.Number `json:"intValue,omitempty"`
+	DoubleValue *json.Number `json:"doubleValue,omitempty"`
+}
+
+// OTLPKeyValue is a named attribute.
+type OTLPKeyValue struct {
+	Key   string       `json:"key"`
+	Value OTLPAnyValue `json:"value"`
+}
+
+// OTLPStatus is the outcome of a span. Code is
+// either numeric or the enum name, depending on
+// the exporter.
+type OTLPStatus struct {
+	Code    interface{} `json:"code"`
+	Message string      `json:"message"`
+}
+
+// OTLPLink points from a span to another span
+// it causally depends on.
+type OTLPLink struct {
+	TraceID string `json:"traceId"`
+	SpanID  string `json:"spanId"`
+}
+
+// OTLPSpan is one span of an OTLP JSON export.
+type OTLPSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId"`
+	Name              string         `json:"name"`
+	StartTimeUnixNano json.Number    `json:"startTimeUnixNano"`
+	EndTimeUnixNano   json.Number    `json:"endTimeUnixNano"`
+	Attributes        []OTLPKeyValue `json:"attributes"`
+	Links             []OTLPLink     `json:"links"`
+	Status            OTLPStatus     `json:"status"`
+}
+
+// OTLPScopeSpans groups spans by instrumentation
+// scope. Older exporters call these instrumentation
+// library spans.
+type OTLPScopeSpans struct {
+	Spans []*OTLPSpan `json:"spans"`
+}
+
+// OTLPResource describes the entity, usually
+// a service, that emitted spans.
+type OTLPResource struct {
+	Attributes []OTLPKeyValue `json:"attributes"`
+}
+
+// OTLPResourceSpans holds all spans of one resource.
+type OTLPResourceSpans struct {
+	Resource                    OTLPResource      `json:"resource"`
+	ScopeSpans                  []*OTLPScopeSpans `json:"scopeSpans"`
+	InstrumentationLibrarySpans []*OTLPScopeSpans `json:"instrumentationLibrarySpans"`
+}
+
+// OTLPExport is one OTLP JSON trace export, as
+// written by the OpenTelemetry Collector's file
+// exporter or returned by trace backends.
+type OTLPExport struct {
+	ResourceSpans []*OTLPResourceSpans `json:"resourceSpans"`
+}
+
+// OTLP reads OpenTelemetry traces exported in OTLP
+// JSON, one trace per run. Failure is the predicate
+// labelling traces as failed.
+type OTLP struct {
+	Run       string
+	InputPath string
+	Failure   string
+	Output
+}
+
+// MollyUpstream reads the output of upstream
+// (unforked) Molly.
+type MollyUpstream struct {
+	Run       string
+	OutputDir string
+	Output
+}
+
+// Molly reads the output of our Molly fork. With
+// Stream set, provenance is not kept in memory but
+// streamed from its files into the graph database.
+type Molly struct {
+	Run       string
+	OutputDir string
+	Stream    bool
+	fsys      FS
+	Output
 }
@@ -0,0 +1,300 @@
+package faultinjectors
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Structs.
+
+// ednReader reads EDN values as emitted by Jepsen into
+// plain Go values: maps become map[string]interface{}
+// (keys rendered as strings, keywords without colon),
+// vectors, lists, and sets become []interface{},
+// keywords become ednKeyword, symbols strings, integers
+// int64, and decimals float64. Tags of tagged literals
+// are dropped.
+type ednReader struct {
+	src  []rune
+	pos  int
+	line int
+}
+
+// ednKeyword is a keyword, without its colon. Keeping
+// keywords apart from strings tells values describing
+// a fault, such as :majority, from node names.
+type ednKeyword string
+
+// Functions.
+
+// newEDNReader prepares reading EDN values from content.
+func newEDNReader(content []byte) *ednReader {
+
+	return &ednReader{
+		src:  []rune(string(content)),
+		line: 1,
+	}
+}
+
+// errorf reports a syntax error at the current line.
+func (r *ednReader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("EDN line %d: %s", r.line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace advances past whitespace, commas, and comments.
+func (r *ednReader) skipSpace() {
+
+	for r.pos < len(r.src) {
+
+		c := r.src[r.pos]
+
+		if c == ';' {
+
+			for (r.pos < len(r.src)) && (r.src[r.pos] != '\n') {
+				r.pos++
+			}
+		} else if unicode.IsSpace(c) || (c == ',') {
+
+			if c == '\n' {
+				r.line++
+			}
+			r.pos++
+		} else {
+			return
+		}
+	}
+}
+
+// isDelim reports whether c ends a token.
+func isDelim(c rune) bool {
+	return unicode.IsSpace(c) || strings.ContainsRune(",;()[]{}\"", c)
+}
+
+// token reads characters up to the next delimiter.
+func (r *ednReader) token() string {
+
+	start := r.pos
+	for (r.pos < len(r.src)) && !isDelim(r.src[r.pos]) {
+		r.pos++
+	}
+
+	return string(r.src[start:r.pos])
+}
+
+// Next reads the next top-level value, skipping
+// discarded ones. It returns io.EOF once the input
+// is exhausted.
+func (r *ednReader) Next() (interface{}, error) {
+
+	for {
+
+		r.skipSpace()
+		if r.pos >= len(r.src) {
+			return nil, io.EOF
+		}
+
+		val, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, discarded := val.(ednDiscard); !discarded {
+			return val, nil
+		}
+	}
+}
+
+// seq reads values up to the closing delimiter end.
+func (r *ednReader) seq(end rune) ([]interface{}, error) {
+
+	vals := make([]interface{}, 0, 4)
+
+	for {
+
+		r.skipSpace()
+		if r.pos >= len(r.src) {
+			return nil, r.errorf("unexpected end of input, expected '%c'", end)
+		}
+
+		if r.src[r.pos] == end {
+			r.pos++
+			return vals, nil
+		}
+
+		val, err := r.value()
+		if err != nil {
+			return nil, err
+		}
+
+		// Drop values marked by the discard macro.
+		if _, discarded := val.(ednDiscard); discarded {
+			continue
+		}
+
+		vals = append(vals, val)
+	}
+}
+
+// ednDiscard marks a value read after #_.
+type ednDiscard struct{}
+
+// value reads exactly one value.
+func (r *ednReader) value() (interface{}, error) {
+
+	r.skipSpace()
+	if r.pos >= len(r.src) {
+		return nil, r.errorf("unexpected end of input")
+	}
+
+	c := r.src[r.pos]
+
+	switch {
+
+	case c == '(':
+		r.pos++
+		return r.seq(')')
+
+	case c == '[':
+		r.pos++
+		return r.seq(']')
+
+	case c == '{':
+		r.pos++
+		vals, err := r.seq('}')
+		if err != nil {
+			return nil, err
+		}
+
+		if (len(vals) % 2) != 0 {
+			return nil, r.errorf("map with odd number of forms")
+		}
+
+		m := make(map[string]interface{}, (len(vals) / 2))
+		for i := 0; i < len(vals); i += 2 {
+			m[ednKey(vals[i])] = vals[(i + 1)]
+		}
+
+		return m, nil
+
+	case c == '"':
+		return r.str()
+
+	case c == '#':
+		r.pos++
+		if r.pos >= len(r.src) {
+			return nil, r.errorf("unexpected end of input after '#'")
+		}
+
+		switch r.src[r.pos] {
+		case '{':
+			r.pos++
+			return r.seq('}')
+		case '_':
+			r.pos++
+			_, err := r.value()
+			return ednDiscard{}, err
+		default:
+			// Tagged literal: drop tag, keep value.
+			r.token()
+			return r.value()
+		}
+
+	case c == ':':
+		r.pos++
+		return ednKeyword(r.token()), nil
+
+	case c == '\\':
+		r.pos++
+		if r.pos >= len(r.src) {
+			return nil, r.errorf("unexpected end of input after '\\'")
+		}
+		r.pos++
+		return string(r.src[(r.pos-1)]) + r.token(), nil
+
+	case strings.ContainsRune(")]}", c):
+		return nil, r.errorf("unexpected '%c'", c)
+	}
+
+	tok := r.token()
+
+	switch tok {
+	case "nil":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+
+	if i, err := strconv.ParseInt(strings.TrimSuffix(tok, "N"), 10, 64); err == nil {
+		return i, nil
+	}
+
+	if f, err := strconv.ParseFloat(strings.TrimSuffix(tok, "M"), 64); err == nil {
+		return f, nil
+	}
+
+	// Symbol.
+	return tok, nil
+}
+
+// str reads a string literal.
+func (r *ednReader) str() (string, error) {
+
+	r.pos++
+
+	var b strings.Builder
+
+	for r.pos < len(r.src) {
+
+		c := r.src[r.pos]
+		r.pos++
+
+		switch c {
+
+		case '"':
+			return b.String(), nil
+
+		case '\n':
+			r.line++
+			b.WriteRune(c)
+
+		case '\\':
+			if r.pos >= len(r.src) {
+				return "", r.errorf("unterminated string")
+			}
+
+			esc := r.src[r.pos]
+			r.pos++
+
+			switch esc {
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			case 'r':
+				b.WriteRune('\r')
+			default:
+				b.WriteRune(esc)
+			}
+
+		default:
+			b.WriteRune(c)
+		}
+	}
+
+	return "", r.errorf("unterminated string")
+}
+
+// ednKey renders a map key as string.
+func ednKey(key interface{}) string {
+
+	if s, ok := key.(string); ok {
+		return s
+	}
+
+	return fmt.Sprintf("%v", key)
+}
@@ -0,0 +1,102 @@
+package faultinjectors
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Functions.
+
+func TestEDNReader(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		edn    string
+		values []interface{}
+		err    string
+	}{
+		{
+			name: "operation",
+			edn:  `{:type :ok, :f :write, :value 1, :process 0, :time 20}`,
+			values: []interface{}{
+				map[string]interface{}{
+					"type":    ednKeyword("ok"),
+					"f":       ednKeyword("write"),
+					"value":   int64(1),
+					"process": int64(0),
+					"time":    int64(20),
+				},
+			},
+		},
+		{
+			name: "collections and scalars",
+			edn:  `[1 2.5 "n\"1" nil true] (:a b) #{"n1"} #_ :gone #inst "2020"`,
+			values: []interface{}{
+				[]interface{}{int64(1), 2.5, "n\"1", nil, true},
+				[]interface{}{ednKeyword("a"), "b"},
+				[]interface{}{"n1"},
+				"2020",
+			},
+		},
+		{
+			name: "comments and commas",
+			edn:  "; history\n{\"n1\" :killed,, \"n2\" :killed} ; done\n",
+			values: []interface{}{
+				map[string]interface{}{"n1": ednKeyword("killed"), "n2": ednKeyword("killed")},
+			},
+		},
+		{
+			name: "unterminated map",
+			edn:  "{:type :ok,\n :f :write",
+			err:  "EDN line 2: unexpected end of input, expected '}'",
+		},
+		{
+			name: "odd map",
+			edn:  `{:type}`,
+			err:  "EDN line 1: map with odd number of forms",
+		},
+		{
+			name: "unterminated string",
+			edn:  `["n1`,
+			err:  "EDN line 1: unterminated string",
+		},
+	}
+
+	for _, tt := range tests {
+
+		r := newEDNReader([]byte(tt.edn))
+		values := make([]interface{}, 0, len(tt.values))
+
+		var err error
+		for {
+
+			var val interface{}
+			val, err = r.Next()
+			if err != nil {
+				break
+			}
+
+			values = append(values, val)
+		}
+
+		if tt.err != "" {
+
+			if (err == io.EOF) || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: reading returned %v, expected '%s'", tt.name, err, tt.err)
+			}
+
+			continue
+		}
+
+		if err != io.EOF {
+			t.Errorf("%s: reading failed: %v", tt.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(values, tt.values) {
+			t.Errorf("%s: values are %#v, expected %#v", tt.name, values, tt.values)
+		}
+	}
+}
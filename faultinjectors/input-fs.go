@@ -0,0 +1,505 @@
+package faultinjectors
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// Interfaces.
+
+// FS gives read access to fault injector output, be it
+// a directory or an archive of one. Names are slash-
+// separated and relative to the root of the output,
+// "." denoting the root itself. Missing files yield
+// errors satisfying os.IsNotExist.
+type FS interface {
+	Open(name string) (io.ReadCloser, error)
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error)
+	Path(name string) string
+}
+
+// Structs.
+
+// dirFS is an FS over a directory.
+type dirFS struct {
+	root string
+}
+
+// archiveFS is an FS over a tar, gzipped tar, or zip
+// archive. It indexes the archive's members once and
+// opens the archive anew for each file read, so that
+// no file content is held in memory.
+type archiveFS struct {
+	path    string
+	root    string
+	files   map[string]os.FileInfo
+	dirs    map[string]bool
+	members map[string]string
+	open    func(member string) (io.ReadCloser, error)
+}
+
+// dirInfo describes a directory that is only
+// implied by the members of an archive.
+type dirInfo struct {
+	name string
+}
+
+// archiveReader reads one member of an archive and
+// closes the archive file along with it.
+type archiveReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Functions.
+
+// ArchiveExt returns the extension marking path as an
+// archive Nemo reads transparently, or the empty string
+// if path is no such archive.
+func ArchiveExt(path string) string {
+
+	lower := strings.ToLower(path)
+
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return path[(len(path) - len(ext)):]
+		}
+	}
+
+	return ""
+}
+
+// OpenFS opens the fault injector output at path,
+// which is either a directory or a .tar, .tar.gz,
+// .tgz, or .zip archive of one.
+func OpenFS(path string) (FS, error) {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &dirFS{root: path}, nil
+	}
+
+	switch strings.ToLower(ArchiveExt(path)) {
+	case ".tar.gz", ".tgz":
+		return newTarFS(path, true)
+	case ".tar":
+		return newTarFS(path, false)
+	case ".zip":
+		return newZipFS(path)
+	}
+
+	return nil, fmt.Errorf("'%s' is neither a directory nor a .tar, .tar.gz, .tgz, or .zip archive", path)
+}
+
+// openInput opens the fault injector output at path,
+// which may also be a single file. It returns the FS
+// and the name of that file in it, or "." otherwise.
+func openInput(path string) (FS, string, error) {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !info.IsDir() && (ArchiveExt(path) == "") {
+		return &dirFS{root: filepath.Dir(path)}, filepath.Base(path), nil
+	}
+
+	fsys, err := OpenFS(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return fsys, ".", nil
+}
+
+// ReadFile reads the whole file name from fsys.
+func ReadFile(fsys FS, name string) ([]byte, error) {
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// Open
+func (d *dirFS) Open(name string) (io.ReadCloser, error) {
+	return os.Open(d.Path(name))
+}
+
+// Stat
+func (d *dirFS) Stat(name string) (os.FileInfo, error) {
+	return os.Stat(d.Path(name))
+}
+
+// ReadDir
+func (d *dirFS) ReadDir(name string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(d.Path(name))
+}
+
+// Path
+func (d *dirFS) Path(name string) string {
+	return filepath.Join(d.root, filepath.FromSlash(name))
+}
+
+// cleanMember normalizes the name of an archive member,
+// rejecting names that point outside of the archive.
+func cleanMember(member string) (string, bool) {
+
+	name := path.Clean(strings.TrimPrefix(strings.Replace(member, "\\", "/", -1), "./"))
+
+	if (name == ".") || (name == "..") || strings.HasPrefix(name, "../") || strings.HasPrefix(name, "/") {
+		return "", false
+	}
+
+	return name, true
+}
+
+// newArchiveFS prepares an empty index of the
+// archive at archivePath.
+func newArchiveFS(archivePath string, open func(member string) (io.ReadCloser, error)) *archiveFS {
+
+	return &archiveFS{
+		path:    archivePath,
+		files:   make(map[string]os.FileInfo),
+		dirs:    map[string]bool{".": true},
+		members: make(map[string]string),
+		open:    open,
+	}
+}
+
+// addDir records directory name and all its parents.
+func (a *archiveFS) addDir(name string) {
+
+	for (name != ".") && !a.dirs[name] {
+		a.dirs[name] = true
+		name = path.Dir(name)
+	}
+}
+
+// addFile records regular file member.
+func (a *archiveFS) addFile(member string, info os.FileInfo) {
+
+	name, ok := cleanMember(member)
+	if !ok {
+		return
+	}
+
+	a.files[name] = info
+	a.members[name] = member
+	a.addDir(path.Dir(name))
+}
+
+// descend makes the single top-level directory of an
+// archive its root for as long as there is one, as
+// archives commonly wrap their content in a directory.
+func (a *archiveFS) descend() {
+
+	for {
+
+		children := a.children(".")
+		if (len(children) != 1) || !a.dirs[children[0]] {
+			return
+		}
+
+		prefix := children[0] + "/"
+
+		files := make(map[string]os.FileInfo, len(a.files))
+		members := make(map[string]string, len(a.members))
+		for name := range a.files {
+			files[strings.TrimPrefix(name, prefix)] = a.files[name]
+			members[strings.TrimPrefix(name, prefix)] = a.members[name]
+		}
+
+		dirs := map[string]bool{".": true}
+		for name := range a.dirs {
+			if strings.HasPrefix(name, prefix) {
+				dirs[strings.TrimPrefix(name, prefix)] = true
+			}
+		}
+
+		a.root = path.Join(a.root, children[0])
+		a.files = files
+		a.members = members
+		a.dirs = dirs
+	}
+}
+
+// children returns the names of all files and
+// directories directly inside directory dir.
+func (a *archiveFS) children(dir string) []string {
+
+	children := make([]string, 0, 10)
+
+	for name := range a.files {
+		if path.Dir(name) == dir {
+			children = append(children, name)
+		}
+	}
+
+	for name := range a.dirs {
+		if (name != ".") && (path.Dir(name) == dir) {
+			children = append(children, name)
+		}
+	}
+
+	sort.Strings(children)
+
+	return children
+}
+
+// notExist reports that name is not in the archive.
+func (a *archiveFS) notExist(op string, name string) error {
+
+	return &os.PathError{
+		Op:   op,
+		Path: a.Path(name),
+		Err:  os.ErrNotExist,
+	}
+}
+
+// Open
+func (a *archiveFS) Open(name string) (io.ReadCloser, error) {
+
+	member, found := a.members[path.Clean(name)]
+	if !found {
+		return nil, a.notExist("open", name)
+	}
+
+	return a.open(member)
+}
+
+// Stat
+func (a *archiveFS) Stat(name string) (os.FileInfo, error) {
+
+	name = path.Clean(name)
+
+	if info, found := a.files[name]; found {
+		return info, nil
+	}
+
+	if a.dirs[name] {
+		return &dirInfo{name: path.Base(name)}, nil
+	}
+
+	return nil, a.notExist("stat", name)
+}
+
+// ReadDir
+func (a *archiveFS) ReadDir(name string) ([]os.FileInfo, error) {
+
+	name = path.Clean(name)
+
+	if !a.dirs[name] {
+		return nil, a.notExist("readdir", name)
+	}
+
+	children := a.children(name)
+
+	infos := make([]os.FileInfo, len(children))
+	for i := range children {
+		infos[i], _ = a.Stat(children[i])
+	}
+
+	return infos, nil
+}
+
+// Path renders name as member of the archive.
+func (a *archiveFS) Path(name string) string {
+	return a.path + "/" + path.Join(a.root, name)
+}
+
+// newTarFS indexes the (gzipped) tar archive at
+// archivePath. As tar archives cannot be read at
+// random, opening a file scans the archive up to it.
+func newTarFS(archivePath string, gzipped bool) (*archiveFS, error) {
+
+	// openTar returns a reader positioned at the
+	// beginning of the archive's members.
+	openTar := func() (*tar.Reader, []io.Closer, error) {
+
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !gzipped {
+			return tar.NewReader(f), []io.Closer{f}, nil
+		}
+
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("Failed to decompress '%s': %v", archivePath, err)
+		}
+
+		return tar.NewReader(gz), []io.Closer{gz, f}, nil
+	}
+
+	a := newArchiveFS(archivePath, func(member string) (io.ReadCloser, error) {
+
+		tr, closers, err := openTar()
+		if err != nil {
+			return nil, err
+		}
+
+		r := &archiveReader{
+			Reader:  tr,
+			closers: closers,
+		}
+
+		for {
+
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				r.Close()
+				return nil, fmt.Errorf("Failed reading archive '%s': %v", archivePath, err)
+			}
+
+			if hdr.Name == member {
+				return r, nil
+			}
+		}
+
+		r.Close()
+
+		return nil, fmt.Errorf("Archive '%s' changed while reading it, member '%s' is gone", archivePath, member)
+	})
+
+	tr, closers, err := openTar()
+	if err != nil {
+		return nil, err
+	}
+	defer (&archiveReader{closers: closers}).Close()
+
+	for {
+
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Failed reading archive '%s': %v", archivePath, err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			a.addFile(hdr.Name, hdr.FileInfo())
+		case tar.TypeDir:
+			if name, ok := cleanMember(hdr.Name); ok {
+				a.addDir(name)
+			}
+		}
+	}
+
+	a.descend()
+
+	return a, nil
+}
+
+// newZipFS indexes the zip archive at archivePath.
+func newZipFS(archivePath string) (*archiveFS, error) {
+
+	a := newArchiveFS(archivePath, func(member string) (io.ReadCloser, error) {
+
+		zr, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("Failed reading archive '%s': %v", archivePath, err)
+		}
+
+		for _, f := range zr.File {
+
+			if f.Name != member {
+				continue
+			}
+
+			r, err := f.Open()
+			if err != nil {
+				zr.Close()
+				return nil, fmt.Errorf("Failed reading '%s' from archive '%s': %v", member, archivePath, err)
+			}
+
+			return &archiveReader{
+				Reader:  r,
+				closers: []io.Closer{r, zr},
+			}, nil
+		}
+
+		zr.Close()
+
+		return nil, fmt.Errorf("Archive '%s' changed while reading it, member '%s' is gone", archivePath, member)
+	})
+
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed reading archive '%s': %v", archivePath, err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+
+		if strings.HasSuffix(f.Name, "/") {
+			if name, ok := cleanMember(f.Name); ok {
+				a.addDir(name)
+			}
+		} else if f.Mode().IsRegular() {
+			a.addFile(f.Name, f.FileInfo())
+		}
+	}
+
+	a.descend()
+
+	return a, nil
+}
+
+// Close closes the member and the archive.
+func (r *archiveReader) Close() error {
+
+	var err error
+
+	for _, c := range r.closers {
+		if cErr := c.Close(); (cErr != nil) && (err == nil) {
+			err = cErr
+		}
+	}
+
+	return err
+}
+
+// Name
+func (d *dirInfo) Name() string { return d.name }
+
+// Size
+func (d *dirInfo) Size() int64 { return 0 }
+
+// Mode
+func (d *dirInfo) Mode() os.FileMode { return (os.ModeDir | 0755) }
+
+// ModTime
+func (d *dirInfo) ModTime() time.Time { return time.Time{} }
+
+// IsDir
+func (d *dirInfo) IsDir() bool { return true }
+
+// Sys
+func (d *dirInfo) Sys() interface{} { return nil }
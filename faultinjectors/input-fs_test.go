@@ -0,0 +1,190 @@
+package faultinjectors
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"path/filepath"
+)
+
+// Functions.
+
+// archiveMembers are the members of each test archive.
+// The output is wrapped in a directory, and three members
+// try to point outside of the archive.
+var archiveMembers = []struct {
+	name    string
+	content string
+}{
+	{"out/runs.json", "[]"},
+	{"out/run_0/history.edn", "{:type :ok}"},
+	{"../evil.json", "evil"},
+	{"out/../../evil.json", "evil"},
+	{"/etc/evil.json", "evil"},
+}
+
+// writeTar writes archiveMembers to a (gzipped) tar archive.
+func writeTar(file string, gzipped bool) error {
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var tw *tar.Writer
+	if gzipped {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(f)
+	}
+	defer tw.Close()
+
+	for _, m := range archiveMembers {
+
+		err := tw.WriteHeader(&tar.Header{
+			Name:     m.name,
+			Mode:     0644,
+			Size:     int64(len(m.content)),
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tw.Write([]byte(m.content))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeZip writes archiveMembers to a zip archive.
+func writeZip(file string) error {
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	defer zw.Close()
+
+	for _, m := range archiveMembers {
+
+		w, err := zw.Create(m.name)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write([]byte(m.content))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestArchiveFS(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "nemo-archive")
+	if err != nil {
+		t.Fatalf("creating directory failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name  string
+		write func(file string) error
+	}{
+		{"out.tar", func(file string) error { return writeTar(file, false) }},
+		{"out.tar.gz", func(file string) error { return writeTar(file, true) }},
+		{"out.tgz", func(file string) error { return writeTar(file, true) }},
+		{"out.zip", writeZip},
+	}
+
+	for _, tt := range tests {
+
+		file := filepath.Join(dir, tt.name)
+
+		err := tt.write(file)
+		if err != nil {
+			t.Fatalf("%s: writing archive failed: %v", tt.name, err)
+		}
+
+		fsys, err := OpenFS(file)
+		if err != nil {
+			t.Fatalf("%s: opening archive failed: %v", tt.name, err)
+		}
+
+		// Only the wrapping directory's content remains.
+		infos, err := fsys.ReadDir(".")
+		if err != nil {
+			t.Fatalf("%s: reading root failed: %v", tt.name, err)
+		}
+
+		names := make([]string, len(infos))
+		for i := range infos {
+			names[i] = infos[i].Name()
+		}
+
+		if !reflect.DeepEqual(names, []string{"run_0", "runs.json"}) {
+			t.Errorf("%s: root contains %v, expected [run_0 runs.json]", tt.name, names)
+		}
+
+		content, err := ReadFile(fsys, "run_0/history.edn")
+		if (err != nil) || (string(content) != "{:type :ok}") {
+			t.Errorf("%s: history is '%s' (%v), expected '{:type :ok}'", tt.name, content, err)
+		}
+
+		for _, name := range []string{"../evil.json", "evil.json", "etc/evil.json", "../../evil.json"} {
+
+			if _, err := fsys.Open(name); !os.IsNotExist(err) {
+				t.Errorf("%s: opening '%s' returned %v, expected it not to exist", tt.name, name, err)
+			}
+		}
+
+		if fsys.Path("runs.json") != (file + "/out/runs.json") {
+			t.Errorf("%s: path of runs.json is '%s'", tt.name, fsys.Path("runs.json"))
+		}
+	}
+}
+
+func TestCleanMember(t *testing.T) {
+
+	tests := []struct {
+		member string
+		name   string
+		ok     bool
+	}{
+		{"out/runs.json", "out/runs.json", true},
+		{"./out/runs.json", "out/runs.json", true},
+		{"out\\run_0\\history.edn", "out/run_0/history.edn", true},
+		{"out/a/../runs.json", "out/runs.json", true},
+		{"../runs.json", "", false},
+		{"out/../../runs.json", "", false},
+		{"..\\runs.json", "", false},
+		{"/etc/passwd", "", false},
+		{"./", "", false},
+		{"..", "", false},
+	}
+
+	for _, tt := range tests {
+
+		name, ok := cleanMember(tt.member)
+		if (name != tt.name) || (ok != tt.ok) {
+			t.Errorf("member '%s' cleaned to '%s' (%t), expected '%s' (%t)", tt.member, name, ok, tt.name, tt.ok)
+		}
+	}
+}
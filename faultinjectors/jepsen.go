@@ -0,0 +1,1046 @@
+package faultinjectors
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Structs.
+
+// jepsenClientOp is a client operation of a Jepsen
+// history with its invocation and completion paired up.
+type jepsenClientOp struct {
+	process string
+	node    string
+	f       string
+	num     int
+	value   interface{}
+	invoked uint
+	done    uint
+	result  string
+}
+
+// jepsenTest holds the parts of a test's configuration
+// needed to interpret its history: the database nodes, in
+// the order Jepsen binds client processes to them, and the
+// name of the nemesis.
+type jepsenTest struct {
+	nodes   []string
+	nemesis string
+}
+
+// Constants.
+
+const (
+	// nemesisCrash marks nemesis operations that
+	// kill, crash, or pause nodes.
+	nemesisCrash = "crash"
+
+	// nemesisPartition marks nemesis operations
+	// that partition the network.
+	nemesisPartition = "partition"
+)
+
+// Functions.
+
+// findFile returns the first of names that exists in dir
+// of fsys, or the empty string if none does.
+func findFile(fsys FS, dir string, names ...string) string {
+
+	for _, name := range names {
+
+		file := path.Join(dir, name)
+		if _, err := fsys.Stat(file); err == nil {
+			return file
+		}
+	}
+
+	return ""
+}
+
+// readValues reads all top-level values of an EDN,
+// JSON, or NDJSON file. A single top-level vector or
+// array is unwrapped into its elements.
+func readValues(file string, content []byte) ([]interface{}, error) {
+
+	vals := make([]interface{}, 0, 100)
+
+	if strings.HasSuffix(file, ".edn") {
+
+		r := newEDNReader(content)
+		for {
+
+			val, err := r.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+
+			vals = append(vals, val)
+		}
+	} else {
+
+		dec := json.NewDecoder(strings.NewReader(string(content)))
+		dec.UseNumber()
+		for {
+
+			var val interface{}
+
+			err := dec.Decode(&val)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+
+			vals = append(vals, val)
+		}
+	}
+
+	if len(vals) == 1 {
+		if inner, ok := vals[0].([]interface{}); ok {
+			return inner, nil
+		}
+	}
+
+	return vals, nil
+}
+
+// toInt converts numbers as read from EDN or JSON.
+func toInt(v interface{}) (int64, bool) {
+
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		if f, err := n.Float64(); err == nil {
+			return int64(f), true
+		}
+	}
+
+	return 0, false
+}
+
+// toString converts strings and keywords as read
+// from EDN or JSON.
+func toString(v interface{}) (string, bool) {
+
+	switch s := v.(type) {
+	case string:
+		return s, true
+	case ednKeyword:
+		return string(s), true
+	}
+
+	return "", false
+}
+
+// toJepsenOp converts one history entry into an operation.
+func toJepsenOp(v interface{}) (*JepsenOp, error) {
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected operation map, found %T", v)
+	}
+
+	op := &JepsenOp{
+		Index: -1,
+		Time:  -1,
+		Value: m["value"],
+	}
+
+	op.Type, _ = toString(m["type"])
+	if op.Type == "" {
+		return nil, fmt.Errorf("operation lacks type")
+	}
+
+	op.F = fmt.Sprintf("%v", m["f"])
+
+	if i, ok := toInt(m["index"]); ok {
+		op.Index = i
+	}
+
+	if t, ok := toInt(m["time"]); ok {
+		op.Time = t
+	}
+
+	if p, ok := toInt(m["process"]); ok {
+		op.Process = strconv.FormatInt(p, 10)
+	} else if p, ok := toString(m["process"]); ok {
+		op.Process = p
+	} else {
+		return nil, fmt.Errorf("operation lacks process")
+	}
+
+	return op, nil
+}
+
+// readJepsenOps reads all operations from file.
+func readJepsenOps(file string, content []byte) ([]*JepsenOp, error) {
+
+	vals, err := readValues(file, content)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := make([]*JepsenOp, len(vals))
+	for i := range vals {
+
+		ops[i], err = toJepsenOp(vals[i])
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %v", i, err)
+		}
+	}
+
+	return ops, nil
+}
+
+// readJepsenStatus determines from the checker results
+// whether a test succeeded.
+func readJepsenStatus(file string, content []byte) (string, error) {
+
+	vals, err := readValues(file, content)
+	if err != nil {
+		return "", err
+	}
+
+	if len(vals) == 0 {
+		return "", fmt.Errorf("no results found")
+	}
+
+	results, ok := vals[0].(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("expected results map, found %T", vals[0])
+	}
+
+	valid, found := results["valid?"]
+	if !found {
+		valid = results["valid"]
+	}
+
+	if valid == true {
+		return "success", nil
+	}
+
+	return "failure", nil
+}
+
+// readJepsenTest reads the node list and the nemesis
+// name from an exported test configuration.
+func readJepsenTest(file string, content []byte) (*jepsenTest, error) {
+
+	vals, err := readValues(file, content)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vals) == 0 {
+		return nil, fmt.Errorf("no test configuration found")
+	}
+
+	config, ok := vals[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected test map, found %T", vals[0])
+	}
+
+	test := &jepsenTest{
+		nodes: make([]string, 0, 5),
+	}
+
+	if nodes, ok := config["nodes"].([]interface{}); ok {
+		for i := range nodes {
+
+			if node, ok := toString(nodes[i]); ok {
+				test.nodes = append(test.nodes, node)
+			}
+		}
+	}
+
+	test.nemesis, _ = toString(config["nemesis"])
+
+	return test, nil
+}
+
+// nodeNames collects the node names mentioned in v:
+// strings inside lists and sets, and the keys of maps.
+// Keywords, plain strings, and scalar map values, as in
+// :majority or {"n1" :killed}, describe the fault.
+func nodeNames(v interface{}, names map[string]bool) {
+
+	switch val := v.(type) {
+	case []interface{}:
+		for i := range val {
+
+			if name, ok := val[i].(string); ok {
+				names[name] = true
+			} else {
+				nodeNames(val[i], names)
+			}
+		}
+	case map[string]interface{}:
+		for k := range val {
+			names[k] = true
+			nodeNames(val[k], names)
+		}
+	}
+}
+
+// targetNodes returns the nodes of nodeSet that nemesis
+// value v names, including a single node name.
+func targetNodes(v interface{}, nodeSet map[string]bool) []string {
+
+	names := make(map[string]bool)
+	if name, ok := v.(string); ok {
+		names[name] = true
+	} else {
+		nodeNames(faultValue(v), names)
+	}
+
+	for name := range names {
+		if !nodeSet[name] {
+			delete(names, name)
+		}
+	}
+
+	return sortedNames(names)
+}
+
+// faultValue strips the tag Jepsen puts in front of
+// some nemesis results, as in [:isolated {...}].
+func faultValue(v interface{}) interface{} {
+
+	tagged, ok := v.([]interface{})
+	if !ok || (len(tagged) != 2) {
+		return v
+	}
+
+	if _, isTag := tagged[0].(ednKeyword); !isTag {
+		if _, isTag = tagged[0].(string); !isTag {
+			return v
+		}
+	}
+
+	switch tagged[1].(type) {
+	case map[string]interface{}, []interface{}:
+		return tagged[1]
+	}
+
+	return v
+}
+
+// sortedNames returns the keys of names in order.
+func sortedNames(names map[string]bool) []string {
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// nemesisKind classifies the faults a nemesis or a nemesis
+// operation injects by its name, empty if the name does
+// not tell.
+func nemesisKind(name string) string {
+
+	switch {
+	case strings.Contains(name, "partition") || strings.Contains(name, "isolat") ||
+		strings.Contains(name, "bridge") || strings.Contains(name, "grudge"):
+		return nemesisPartition
+	case strings.Contains(name, "kill") || strings.Contains(name, "crash") ||
+		strings.Contains(name, "pause") || strings.Contains(name, "stopper") ||
+		strings.Contains(name, "hammer"):
+		return nemesisCrash
+	}
+
+	return ""
+}
+
+// startKind determines the faults the generic :start
+// operations of a history inject: by the nemesis named in
+// the test configuration if any, otherwise by the tags
+// and results of the nemesis' :start operations.
+func startKind(test *jepsenTest, ops []*JepsenOp) string {
+
+	if test != nil {
+		if kind := nemesisKind(test.nemesis); kind != "" {
+			return kind
+		}
+	}
+
+	for _, op := range ops {
+
+		if (op.Process != "nemesis") || (op.F != "start") {
+			continue
+		}
+
+		// Tagged results, such as [:isolated {...}].
+		if tagged, ok := op.Value.([]interface{}); ok && (len(tagged) == 2) {
+			if tag, ok := tagged[0].(ednKeyword); ok {
+				if kind := nemesisKind(string(tag)); kind != "" {
+					return kind
+				}
+			}
+		}
+
+		// Results per node, such as {"n1" :killed}.
+		if results, ok := op.Value.(map[string]interface{}); ok {
+			for node := range results {
+
+				if result, ok := toString(results[node]); ok {
+					if kind := nemesisKind(result); kind != "" {
+						return kind
+					}
+				}
+			}
+		}
+
+		if isGrudge(op.Value) || isComponents(op.Value) {
+			return nemesisPartition
+		}
+	}
+
+	return ""
+}
+
+// faultKind returns the faults nemesis operation f
+// injects, given the kind of :start operations.
+func faultKind(f string, start string) string {
+
+	switch {
+	case f == "start":
+		return start
+	case strings.HasPrefix(f, "stop") || strings.HasPrefix(f, "heal") ||
+		strings.HasPrefix(f, "resume") || strings.HasPrefix(f, "restart"):
+		return ""
+	}
+
+	return nemesisKind(f)
+}
+
+// isGrudge reports whether value is a grudge,
+// i.e., maps each node to a list of nodes.
+func isGrudge(value interface{}) bool {
+
+	grudge, ok := value.(map[string]interface{})
+	if !ok || (len(grudge) == 0) {
+		return false
+	}
+
+	for node := range grudge {
+		if _, ok := grudge[node].([]interface{}); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isComponents reports whether value is a list
+// of network components, i.e., a list of lists.
+func isComponents(value interface{}) bool {
+
+	comps, ok := value.([]interface{})
+	if !ok || (len(comps) == 0) {
+		return false
+	}
+
+	for i := range comps {
+		if _, ok := comps[i].([]interface{}); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// partitionPairs lists all directed node pairs a
+// partition separates. Value is either a grudge,
+// mapping each node to the nodes it cannot reach,
+// or a list of components.
+func partitionPairs(value interface{}) [][2]string {
+
+	pairs := make([][2]string, 0, 8)
+	value = faultValue(value)
+
+	if isGrudge(value) {
+
+		grudge := value.(map[string]interface{})
+
+		from := make([]string, 0, len(grudge))
+		for node := range grudge {
+			from = append(from, node)
+		}
+		sort.Strings(from)
+
+		for _, node := range from {
+
+			cut := make(map[string]bool)
+			nodeNames(grudge[node], cut)
+
+			for _, other := range sortedNames(cut) {
+				pairs = append(pairs, [2]string{other, node})
+			}
+		}
+
+		return pairs
+	}
+
+	if !isComponents(value) {
+		return pairs
+	}
+
+	comps := value.([]interface{})
+	for i := range comps {
+		for j := range comps {
+
+			if i == j {
+				continue
+			}
+
+			from := make(map[string]bool)
+			nodeNames(comps[i], from)
+			to := make(map[string]bool)
+			nodeNames(comps[j], to)
+
+			for _, a := range sortedNames(from) {
+				for _, b := range sortedNames(to) {
+					pairs = append(pairs, [2]string{a, b})
+				}
+			}
+		}
+	}
+
+	return pairs
+}
+
+// processName renders a client process as node name.
+func processName(process string) string {
+	return fmt.Sprintf("p%s", process)
+}
+
+// opArgs renders the arguments identifying a client
+// operation across runs: process, function, and the
+// operation's position among its process' operations.
+func opArgs(op *jepsenClientOp) []string {
+	return []string{processName(op.process), op.f, fmt.Sprintf("%d", op.num)}
+}
+
+// writtenValue returns the value a writing operation
+// installs, and whether op writes at all.
+func writtenValue(op *jepsenClientOp) (string, bool) {
+
+	switch op.f {
+	case "write":
+		return fmt.Sprintf("%v", op.value), true
+	case "cas":
+		if v, ok := op.value.([]interface{}); ok && (len(v) == 2) {
+			return fmt.Sprintf("%v", v[1]), true
+		}
+	}
+
+	return "", false
+}
+
+// jepsenRun maps the history of one test onto a run. The
+// test configuration, if any, provides the database nodes.
+func jepsenRun(iteration uint, status string, test *jepsenTest, ops []*JepsenOp) (*Run, error) {
+
+	// Order events by time, keeping history order on ties.
+	sort.SliceStable(ops, func(i, j int) bool {
+		return ops[i].Time < ops[j].Time
+	})
+
+	nodes := make([]string, 0, 5)
+	nodeSet := make(map[string]bool)
+
+	if (test != nil) && (len(test.nodes) > 0) {
+
+		for _, node := range test.nodes {
+
+			if !nodeSet[node] {
+				nodeSet[node] = true
+				nodes = append(nodes, node)
+			}
+		}
+	} else {
+
+		// Collect names of database nodes from nemesis events.
+		for _, op := range ops {
+			if op.Process == "nemesis" {
+				nodeNames(faultValue(op.Value), nodeSet)
+			}
+		}
+
+		// Drop values that merely describe the fault.
+		for name := range nodeSet {
+			if strings.Contains(name, " ") {
+				delete(nodeSet, name)
+			}
+		}
+
+		nodes = sortedNames(nodeSet)
+	}
+
+	if len(nodes) == 0 {
+		nodes = []string{"db"}
+	}
+
+	start := startKind(test, ops)
+
+	run := &Run{
+		Iteration: iteration,
+		Status:    status,
+		Messages:  make([]*Message, 0, len(ops)),
+	}
+
+	crashes := make([]CrashFailure, 0, 2)
+	omissions := make([]MessageLoss, 0, 4)
+	crashed := make(map[string]bool)
+	var eff uint = 0
+
+	// inject records the faults of nemesis operation f at
+	// step, taken from the first of values naming nodes.
+	inject := func(f string, step uint, values ...interface{}) {
+
+		switch faultKind(f, start) {
+
+		case nemesisCrash:
+
+			for _, value := range values {
+
+				targets := targetNodes(value, nodeSet)
+				if len(targets) == 0 {
+					continue
+				}
+
+				for _, node := range targets {
+
+					crashes = append(crashes, CrashFailure{
+						Node: node,
+						Time: step,
+					})
+					crashed[node] = true
+				}
+
+				if step > eff {
+					eff = step
+				}
+				break
+			}
+
+		case nemesisPartition:
+
+			for _, value := range values {
+
+				found := false
+				for _, pair := range partitionPairs(value) {
+
+					if !nodeSet[pair[0]] || !nodeSet[pair[1]] {
+						continue
+					}
+
+					omissions = append(omissions, MessageLoss{
+						From: pair[0],
+						To:   pair[1],
+						Time: step,
+					})
+					found = true
+				}
+
+				if found {
+
+					if step > eff {
+						eff = step
+					}
+					break
+				}
+			}
+		}
+	}
+
+	pending := make(map[string]*jepsenClientOp)
+	pendingNemesis := make(map[string]*JepsenOp)
+	nemesisSteps := make(map[*JepsenOp]uint)
+	opsPerProcess := make(map[string]int)
+	clientOps := make([]*jepsenClientOp, 0, (len(ops) / 2))
+	processes := make(map[string]bool)
+
+	for i, op := range ops {
+
+		step := uint(i + 1)
+
+		if op.Process == "nemesis" {
+
+			// Nemesis operations are recorded as :info on both
+			// invocation and completion, paired up by function.
+			inv, found := pendingNemesis[op.F]
+			if !found && ((op.Type == "invoke") || (op.Type == "info")) {
+				pendingNemesis[op.F] = op
+				nemesisSteps[op] = step
+				continue
+			}
+			delete(pendingNemesis, op.F)
+
+			// Results name the affected nodes, invocations
+			// may only describe them, as in :majority.
+			if found {
+				inject(op.F, step, op.Value, inv.Value)
+			} else {
+				inject(op.F, step, op.Value)
+			}
+
+			continue
+		}
+
+		if op.Type == "invoke" {
+
+			// Jepsen binds client processes round-robin to nodes.
+			node := nodes[0]
+			if p, err := strconv.Atoi(op.Process); err == nil {
+				node = nodes[(p % len(nodes))]
+			}
+
+			clientOp := &jepsenClientOp{
+				process: op.Process,
+				node:    node,
+				f:       op.F,
+				num:     opsPerProcess[op.Process],
+				value:   op.Value,
+				invoked: step,
+			}
+			opsPerProcess[op.Process]++
+
+			pending[op.Process] = clientOp
+			clientOps = append(clientOps, clientOp)
+			processes[op.Process] = true
+
+			continue
+		}
+
+		clientOp, found := pending[op.Process]
+		if !found {
+			return nil, fmt.Errorf("completion of process %s at event %d without invocation", op.Process, i)
+		}
+		delete(pending, op.Process)
+
+		clientOp.done = step
+		clientOp.result = op.Type
+		if op.Value != nil {
+			clientOp.value = op.Value
+		}
+	}
+
+	// Nemesis operations never completed, such as those of
+	// a separate schedule, take effect when invoked.
+	unpaired := make([]*JepsenOp, 0, len(pendingNemesis))
+	for _, op := range pendingNemesis {
+		unpaired = append(unpaired, op)
+	}
+	sort.Slice(unpaired, func(i, j int) bool {
+		return nemesisSteps[unpaired[i]] < nemesisSteps[unpaired[j]]
+	})
+
+	for _, op := range unpaired {
+
+		inject(op.F, nemesisSteps[op], op.Value)
+	}
+
+	sort.SliceStable(crashes, func(i, j int) bool {
+		return crashes[i].Time < crashes[j].Time
+	})
+
+	sort.SliceStable(omissions, func(i, j int) bool {
+		return omissions[i].Time < omissions[j].Time
+	})
+
+	nodeNamesAll := make([]string, 0, (len(nodes) + len(processes)))
+	nodeNamesAll = append(nodeNamesAll, nodes...)
+	for _, p := range sortedNames(processes) {
+		nodeNamesAll = append(nodeNamesAll, processName(p))
+	}
+
+	run.FailureSpec = &FailureSpec{
+		EOT:        uint(len(ops)),
+		EFF:        eff,
+		MaxCrashes: uint(len(crashed)),
+		Nodes:      &nodeNamesAll,
+		Crashes:    &crashes,
+		Omissions:  &omissions,
+	}
+
+	pre := newProvBuilder()
+	post := newProvBuilder()
+
+	lastInvoke := make(map[string]string)
+	lastOk := make(map[string]string)
+	lastOp := make(map[string]*jepsenClientOp)
+
+	for _, op := range clientOps {
+
+		// Requests travel from client to node, and
+		// definite responses back.
+		run.Messages = append(run.Messages, &Message{
+			Content:  op.f,
+			SendNode: processName(op.process),
+			RecvNode: op.node,
+			SendTime: op.invoked,
+			RecvTime: op.invoked,
+		})
+
+		if (op.result == "ok") || (op.result == "fail") {
+			run.Messages = append(run.Messages, &Message{
+				Content:  op.f,
+				SendNode: op.node,
+				RecvNode: processName(op.process),
+				SendTime: op.done,
+				RecvTime: op.done,
+			})
+		}
+
+		// Antecedent: each process invokes its
+		// operations one after another.
+		inv := pre.goal("invoke", opArgs(op), op.invoked)
+		if prev, found := lastInvoke[op.process]; found {
+			pre.rule("invoke", "next", inv, prev)
+		}
+		lastInvoke[op.process] = inv
+		lastOp[op.process] = op
+
+		if op.result != "ok" {
+			continue
+		}
+
+		// Consequent: an acknowledged operation depends
+		// on its invocation, on its process' previous
+		// acknowledged operation, and, for reads, on the
+		// write whose value it returned.
+		body := []string{post.goal("invoke", opArgs(op), op.invoked)}
+
+		if prev, found := lastOk[op.process]; found {
+			body = append(body, prev)
+		}
+
+		if op.f == "read" && (op.value != nil) {
+
+			read := fmt.Sprintf("%v", op.value)
+
+			var source *jepsenClientOp
+			for _, w := range clientOps {
+
+				if w.invoked >= op.done {
+					break
+				}
+
+				if v, writes := writtenValue(w); writes && (v == read) && (w.result != "fail") {
+					source = w
+				}
+			}
+
+			if source != nil {
+
+				if (source.result == "ok") && (source.done < op.done) {
+					body = append(body, post.goal("ok", opArgs(source), source.done))
+				} else {
+					body = append(body, post.goal("invoke", opArgs(source), source.invoked))
+				}
+			}
+		}
+
+		ok := post.goal("ok", opArgs(op), op.done)
+		post.rule("ok", "async", ok, body...)
+		lastOk[op.process] = ok
+	}
+
+	for _, p := range sortedNames(processes) {
+
+		// Antecedent holds once a process invoked all its operations.
+		preGoal := pre.goal("pre", []string{processName(p)}, lastOp[p].invoked)
+		pre.rule("pre", "", preGoal, lastInvoke[p])
+
+		// Consequent holds for a process whose
+		// final operation was acknowledged.
+		if lastOp[p].result == "ok" {
+			postGoal := post.goal("post", []string{processName(p)}, lastOp[p].done)
+			post.rule("post", "", postGoal, lastOk[p])
+		}
+	}
+
+	run.PreProv = pre.prov
+	run.PostProv = post.prov
+
+	return run, nil
+}
+
+// jepsenTestDirs lists the directories of fsys holding
+// one test each: its root if it contains a history, or
+// the subdirectories that do, in lexicographic order.
+func jepsenTestDirs(fsys FS) ([]string, error) {
+
+	if findFile(fsys, ".", "history.edn", "history.json", "history.jsonl") != "" {
+		return []string{"."}, nil
+	}
+
+	entries, err := fsys.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+
+		// Skip Jepsen's 'latest' symlinks.
+		if !entry.IsDir() {
+			continue
+		}
+
+		dir := entry.Name()
+		if findFile(fsys, dir, "history.edn", "history.json", "history.jsonl") != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no Jepsen history found in '%s' or its subdirectories", fsys.Path("."))
+	}
+
+	return dirs, nil
+}
+
+// LoadOutput reads one Jepsen test per run: its history,
+// its checker results, an optional separate nemesis
+// schedule, and an optional test configuration listing
+// the nodes. Tests may also reside in an archive.
+func (j *Jepsen) LoadOutput() error {
+
+	fsys, err := OpenFS(j.OutputDir)
+	if err != nil {
+		return fmt.Errorf("Could not open Jepsen tests: %v", err)
+	}
+
+	dirs, err := jepsenTestDirs(fsys)
+	if err != nil {
+		return fmt.Errorf("Could not find Jepsen tests: %v", err)
+	}
+
+	j.Output = Output{
+		Runs:             make([]*Run, 0, len(dirs)),
+		RunsIters:        make([]uint, 0, len(dirs)),
+		SuccessRunsIters: make([]uint, 0, len(dirs)),
+		FailedRunsIters:  make([]uint, 0, 3),
+	}
+
+	fingerprint := sha256.New()
+	problems := Problems{}
+
+	for i, dir := range dirs {
+
+		files := []string{
+			findFile(fsys, dir, "history.edn", "history.json", "history.jsonl"),
+			findFile(fsys, dir, "results.edn", "results.json"),
+			findFile(fsys, dir, "schedule.edn", "schedule.json", "schedule.jsonl"),
+			findFile(fsys, dir, "test.edn", "test.json"),
+		}
+
+		if files[1] == "" {
+			return fmt.Errorf("Jepsen test '%s' lacks results.edn or results.json", fsys.Path(dir))
+		}
+
+		contents := make([][]byte, len(files))
+		for k := range files {
+
+			if files[k] == "" {
+				continue
+			}
+
+			contents[k], err = ReadFile(fsys, files[k])
+			if err != nil {
+				return fmt.Errorf("Failed reading '%s': %v", fsys.Path(files[k]), err)
+			}
+
+			fingerprint.Write([]byte(fmt.Sprintf("\n%s\n", files[k])))
+			fingerprint.Write(contents[k])
+		}
+
+		ops, err := readJepsenOps(files[0], contents[0])
+		if err != nil {
+			return fmt.Errorf("Failed to parse history '%s': %v", fsys.Path(files[0]), err)
+		}
+
+		if files[2] != "" {
+
+			schedule, err := readJepsenOps(files[2], contents[2])
+			if err != nil {
+				return fmt.Errorf("Failed to parse nemesis schedule '%s': %v", fsys.Path(files[2]), err)
+			}
+
+			for k := range schedule {
+				schedule[k].Process = "nemesis"
+			}
+			ops = append(ops, schedule...)
+		}
+
+		status, err := readJepsenStatus(files[1], contents[1])
+		if err != nil {
+			return fmt.Errorf("Failed to parse results '%s': %v", fsys.Path(files[1]), err)
+		}
+
+		var test *jepsenTest
+		if files[3] != "" {
+
+			test, err = readJepsenTest(files[3], contents[3])
+			if err != nil {
+				return fmt.Errorf("Failed to parse test configuration '%s': %v", fsys.Path(files[3]), err)
+			}
+		}
+
+		run, err := jepsenRun(uint(i), status, test, ops)
+		if err != nil {
+			return fmt.Errorf("Invalid history '%s': %v", fsys.Path(files[0]), err)
+		}
+
+		// Check the run the history maps to like
+		// runs read from any other fault injector.
+		problems.validateRun(fsys.Path(files[0]), "$", i, run)
+		problems.validateProv(fsys.Path(files[0]), "$.preProv", run.PreProv)
+		problems.validateProv(fsys.Path(files[0]), "$.postProv", run.PostProv)
+
+		run.TimePreHolds = holdTimes(nil, run.PreProv, "pre")
+		run.TimePostHolds = holdTimes(nil, run.PostProv, "post")
+		run.NodesPreHolds = nodeHoldTimes(nil, run.PreProv, "pre")
+		run.NodesPostHolds = nodeHoldTimes(nil, run.PostProv, "post")
+
+		prefixProv(run.PreProv, run.Iteration, "pre")
+		prefixProv(run.PostProv, run.Iteration, "post")
+
+		// Prepare slice for recommendations.
+		run.Recommendation = make([]string, 0, 5)
+
+		j.addRun(run)
+	}
+
+	err = problems.err()
+	if err != nil {
+		return err
+	}
+
+	j.Fingerprint = hex.EncodeToString(fingerprint.Sum(nil))
+
+	return nil
+}
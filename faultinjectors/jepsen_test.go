@@ -0,0 +1,149 @@
+package faultinjectors
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Histories.
+
+// killHistory kills a majority of nodes with Jepsen's
+// node-start-stopper, which records invocation and
+// completion of nemesis operations as :info.
+const killHistory = `
+{:type :invoke, :f :write, :value 1, :process 0, :time 10, :index 0}
+{:type :info, :f :start, :value :majority, :process :nemesis, :time 15, :index 1}
+{:type :info, :f :start, :value {"n1" :killed, "n2" :killed}, :process :nemesis, :time 16, :index 2}
+{:type :ok, :f :write, :value 1, :process 0, :time 20, :index 3}
+{:type :info, :f :stop, :value :all, :process :nemesis, :time 25, :index 4}
+{:type :info, :f :stop, :value {"n1" :started, "n2" :started}, :process :nemesis, :time 26, :index 5}
+{:type :invoke, :f :read, :value nil, :process 1, :time 30, :index 6}
+{:type :ok, :f :read, :value 1, :process 1, :time 40, :index 7}
+`
+
+// partitionHistory isolates n1 from the other nodes
+// with Jepsen's partitioner.
+const partitionHistory = `
+{:type :invoke, :f :write, :value 1, :process 0, :time 10, :index 0}
+{:type :info, :f :start, :value nil, :process :nemesis, :time 15, :index 1}
+{:type :info, :f :start, :value [:isolated {"n1" #{"n2" "n3"}, "n2" #{"n1"}, "n3" #{"n1"}}], :process :nemesis, :time 16, :index 2}
+{:type :ok, :f :write, :value 1, :process 0, :time 20, :index 3}
+{:type :info, :f :stop, :value nil, :process :nemesis, :time 25, :index 4}
+{:type :info, :f :stop, :value :network-healed, :process :nemesis, :time 26, :index 5}
+`
+
+// Functions.
+
+func TestJepsenRun(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		history   string
+		test      *jepsenTest
+		nodes     []string
+		crashes   []CrashFailure
+		omissions []MessageLoss
+		recvNodes []string
+	}{
+		{
+			name:    "kill with node list",
+			history: killHistory,
+			test:    &jepsenTest{nodes: []string{"n1", "n2", "n3"}},
+			nodes:   []string{"n1", "n2", "n3", "p0", "p1"},
+			crashes: []CrashFailure{
+				{Node: "n1", Time: 3},
+				{Node: "n2", Time: 3},
+			},
+			omissions: []MessageLoss{},
+			recvNodes: []string{"n1", "p0", "n2", "p1"},
+		},
+		{
+			name:    "kill without node list",
+			history: killHistory,
+			nodes:   []string{"n1", "n2", "p0", "p1"},
+			crashes: []CrashFailure{
+				{Node: "n1", Time: 3},
+				{Node: "n2", Time: 3},
+			},
+			omissions: []MessageLoss{},
+			recvNodes: []string{"n1", "p0", "n2", "p1"},
+		},
+		{
+			name:    "partition with node list",
+			history: partitionHistory,
+			test:    &jepsenTest{nodes: []string{"n1", "n2", "n3"}, nemesis: "partition-random-node"},
+			nodes:   []string{"n1", "n2", "n3", "p0"},
+			crashes: []CrashFailure{},
+			omissions: []MessageLoss{
+				{From: "n2", To: "n1", Time: 3},
+				{From: "n3", To: "n1", Time: 3},
+				{From: "n1", To: "n2", Time: 3},
+				{From: "n1", To: "n3", Time: 3},
+			},
+			recvNodes: []string{"n1", "p0"},
+		},
+		{
+			name:    "partition without node list",
+			history: partitionHistory,
+			nodes:   []string{"n1", "n2", "n3", "p0"},
+			crashes: []CrashFailure{},
+			omissions: []MessageLoss{
+				{From: "n2", To: "n1", Time: 3},
+				{From: "n3", To: "n1", Time: 3},
+				{From: "n1", To: "n2", Time: 3},
+				{From: "n1", To: "n3", Time: 3},
+			},
+			recvNodes: []string{"n1", "p0"},
+		},
+	}
+
+	for _, tt := range tests {
+
+		ops, err := readJepsenOps("history.edn", []byte(tt.history))
+		if err != nil {
+			t.Fatalf("%s: reading history failed: %v", tt.name, err)
+		}
+
+		run, err := jepsenRun(0, "success", tt.test, ops)
+		if err != nil {
+			t.Fatalf("%s: mapping history failed: %v", tt.name, err)
+		}
+
+		if !reflect.DeepEqual(*run.FailureSpec.Nodes, tt.nodes) {
+			t.Errorf("%s: nodes are %v, expected %v", tt.name, *run.FailureSpec.Nodes, tt.nodes)
+		}
+
+		if !reflect.DeepEqual(*run.FailureSpec.Crashes, tt.crashes) {
+			t.Errorf("%s: crashes are %v, expected %v", tt.name, *run.FailureSpec.Crashes, tt.crashes)
+		}
+
+		if !reflect.DeepEqual(*run.FailureSpec.Omissions, tt.omissions) {
+			t.Errorf("%s: omissions are %v, expected %v", tt.name, *run.FailureSpec.Omissions, tt.omissions)
+		}
+
+		recvNodes := make([]string, len(run.Messages))
+		for i := range run.Messages {
+			recvNodes[i] = run.Messages[i].RecvNode
+		}
+
+		if !reflect.DeepEqual(recvNodes, tt.recvNodes) {
+			t.Errorf("%s: messages received by %v, expected %v", tt.name, recvNodes, tt.recvNodes)
+		}
+	}
+}
+
+func TestReadJepsenTest(t *testing.T) {
+
+	test, err := readJepsenTest("test.edn", []byte(`{:name "etcd", :nodes ["n1" "n2" "n3"], :nemesis :kill}`))
+	if err != nil {
+		t.Fatalf("reading test configuration failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(test.nodes, []string{"n1", "n2", "n3"}) {
+		t.Errorf("nodes are %v, expected [n1 n2 n3]", test.nodes)
+	}
+
+	if nemesisKind(test.nemesis) != nemesisCrash {
+		t.Errorf("nemesis '%s' is of kind '%s', expected '%s'", test.nemesis, nemesisKind(test.nemesis), nemesisCrash)
+	}
+}
@@ -0,0 +1,343 @@
+package faultinjectors
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/awalterschulze/gographviz"
+)
+
+// Functions.
+
+// unquote strips DOT quoting from an identifier or label.
+func unquote(s string) string {
+
+	if unq, err := strconv.Unquote(s); err == nil {
+		return unq
+	}
+
+	return strings.Trim(s, "\"")
+}
+
+// parseTuple splits a tuple label such as
+// "log(a, data, 3)" into table and arguments.
+func parseTuple(label string) (string, []string) {
+
+	open := strings.Index(label, "(")
+	if (open < 0) || !strings.HasSuffix(label, ")") {
+		return label, nil
+	}
+
+	table := label[:open]
+	args := strings.Split(label[(open+1):(len(label)-1)], ",")
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+
+	return table, args
+}
+
+// tupleTime extracts the time step a tuple holds at.
+// In Dedalus, this is the last argument, except for
+// clock tuples, whose last two arguments are send and
+// receive time and which we place at send time.
+func tupleTime(table string, args []string) string {
+
+	if len(args) == 0 {
+		return ""
+	}
+
+	if (table == "clock") && (len(args) >= 2) {
+		return args[(len(args) - 2)]
+	}
+
+	return args[(len(args) - 1)]
+}
+
+// ruleType infers the type of rule from its head goal
+// and body goals, which upstream Molly does not record.
+// A rule deriving a tuple from the same table, location,
+// and previous time step persists state ("next"). A rule
+// none of whose body goals is located at the head's node
+// receives a message ("async").
+func ruleType(head *Goal, body []*Goal) string {
+
+	if (head == nil) || (len(body) == 0) {
+		return ""
+	}
+
+	_, headArgs := parseTuple(head.Label)
+	headTime, errHead := strconv.Atoi(head.Time)
+
+	local := false
+	for _, b := range body {
+
+		_, bodyArgs := parseTuple(b.Label)
+		if (len(headArgs) > 0) && (len(bodyArgs) > 0) && (headArgs[0] == bodyArgs[0]) {
+			local = true
+
+			bodyTime, errBody := strconv.Atoi(b.Time)
+			if (b.Table == head.Table) && (errHead == nil) && (errBody == nil) && (bodyTime+1 == headTime) {
+				return "next"
+			}
+		}
+	}
+
+	if !local {
+		return "async"
+	}
+
+	return ""
+}
+
+// provFromDOT converts the provenance diagram upstream
+// Molly writes for a run into goals, rules, and edges.
+// Nodes named "goal..." are goals, all others rules.
+func provFromDOT(dot []byte) (*ProvData, error) {
+
+	g, err := gographviz.Read(dot)
+	if err != nil {
+		return nil, err
+	}
+
+	provData := &ProvData{
+		Goals: make([]Goal, 0, len(g.Nodes.Nodes)),
+		Rules: make([]Rule, 0, len(g.Nodes.Nodes)),
+		Edges: make([]Edge, 0, len(g.Edges.Edges)),
+	}
+
+	goals := make(map[string]*Goal)
+	rules := make(map[string]bool)
+
+	for _, node := range g.Nodes.Nodes {
+
+		id := unquote(node.Name)
+		label := unquote(node.Attrs["label"])
+		if label == "" {
+			label = id
+		}
+
+		table, args := parseTuple(label)
+
+		if strings.HasPrefix(id, "goal") {
+
+			provData.Goals = append(provData.Goals, Goal{
+				ID:    id,
+				Label: label,
+				Table: table,
+				Time:  tupleTime(table, args),
+			})
+		} else {
+
+			provData.Rules = append(provData.Rules, Rule{
+				ID:    id,
+				Label: label,
+				Table: table,
+			})
+			rules[id] = true
+		}
+	}
+
+	for j := range provData.Goals {
+		goals[provData.Goals[j].ID] = &provData.Goals[j]
+	}
+
+	heads := make(map[string]*Goal)
+	bodies := make(map[string][]*Goal)
+
+	for _, edge := range g.Edges.Edges {
+
+		from := unquote(edge.Src)
+		to := unquote(edge.Dst)
+
+		if goals[from] != nil && rules[to] {
+			heads[to] = goals[from]
+		} else if rules[from] && goals[to] != nil {
+			bodies[from] = append(bodies[from], goals[to])
+		} else {
+			return nil, fmt.Errorf("Edge %s -> %s does not connect a goal and a rule", from, to)
+		}
+
+		provData.Edges = append(provData.Edges, Edge{
+			From: from,
+			To:   to,
+		})
+	}
+
+	for j := range provData.Rules {
+		provData.Rules[j].Type = ruleType(heads[provData.Rules[j].ID], bodies[provData.Rules[j].ID])
+	}
+
+	return provData, nil
+}
+
+// provRootedAt returns the part of provData reachable
+// from goals of table root. If there are none, it
+// returns an empty graph.
+func provRootedAt(provData *ProvData, root string) *ProvData {
+
+	succs := make(map[string][]string)
+	for _, e := range provData.Edges {
+		succs[e.From] = append(succs[e.From], e.To)
+	}
+
+	reached := make(map[string]bool)
+	stack := make([]string, 0, 10)
+	for _, goal := range provData.Goals {
+		if goal.Table == root {
+			stack = append(stack, goal.ID)
+		}
+	}
+
+	for len(stack) > 0 {
+
+		id := stack[(len(stack) - 1)]
+		stack = stack[:(len(stack) - 1)]
+
+		if reached[id] {
+			continue
+		}
+		reached[id] = true
+
+		stack = append(stack, succs[id]...)
+	}
+
+	sub := &ProvData{
+		Goals: make([]Goal, 0, len(reached)),
+		Rules: make([]Rule, 0, len(reached)),
+		Edges: make([]Edge, 0, len(reached)),
+	}
+
+	for _, goal := range provData.Goals {
+		if reached[goal.ID] {
+			sub.Goals = append(sub.Goals, goal)
+		}
+	}
+
+	for _, rule := range provData.Rules {
+		if reached[rule.ID] {
+			sub.Rules = append(sub.Rules, rule)
+		}
+	}
+
+	for _, e := range provData.Edges {
+		if reached[e.From] {
+			sub.Edges = append(sub.Edges, e)
+		}
+	}
+
+	return sub
+}
+
+// LoadOutput reads the output directory of upstream
+// Molly, or an archive of it: runs.json and, where generated, one provenance
+// diagram per run. All inconsistencies are collected
+// and returned as Problems.
+func (m *MollyUpstream) LoadOutput() error {
+
+	fsys, err := OpenFS(m.OutputDir)
+	if err != nil {
+		return fmt.Errorf("Could not open faultInjOut directory: %v", err)
+	}
+
+	fingerprint := sha256.New()
+
+	rawRunsCont, err := ReadFile(fsys, "runs.json")
+	if err != nil {
+		return fmt.Errorf("Could not read runs.json file in faultInjOut directory: %v", err)
+	}
+	fingerprint.Write([]byte("runs.json\n"))
+	fingerprint.Write(rawRunsCont)
+
+	problems := Problems{}
+
+	runsFile := fsys.Path("runs.json")
+	runs := make([]*Run, 0, 10)
+
+	err = json.Unmarshal(rawRunsCont, &runs)
+	if err != nil {
+		problems.addJSONError(runsFile, rawRunsCont, err)
+		return problems
+	}
+
+	if len(runs) == 0 {
+		problems.add(runsFile, "$", "contains no runs")
+	}
+
+	for i := range runs {
+		problems.validateRun(runsFile, fmt.Sprintf("$[%d]", i), i, runs[i])
+	}
+
+	provs := make([]*ProvData, len(runs))
+
+	for i := range runs {
+
+		if runs[i] == nil {
+			continue
+		}
+
+		name := fmt.Sprintf("run_%d_provenance.dot", runs[i].Iteration)
+		provFile := fsys.Path(name)
+
+		rawProvCont, err := ReadFile(fsys, name)
+		if os.IsNotExist(err) {
+			problems.add(provFile, "", "provenance diagram of run %d is missing, please run Molly with provenance diagrams enabled", runs[i].Iteration)
+			continue
+		} else if err != nil {
+			problems.add(provFile, "", "failed reading provenance: %v", err)
+			continue
+		}
+		fingerprint.Write([]byte(fmt.Sprintf("\n%s\n", name)))
+		fingerprint.Write(rawProvCont)
+
+		provs[i], err = provFromDOT(rawProvCont)
+		if err != nil {
+			problems.add(provFile, "", "invalid provenance diagram: %v", err)
+		}
+	}
+
+	err = problems.err()
+	if err != nil {
+		return err
+	}
+
+	m.Output = Output{
+		Runs:             make([]*Run, 0, len(runs)),
+		RunsIters:        make([]uint, 0, len(runs)),
+		SuccessRunsIters: make([]uint, 0, len(runs)),
+		FailedRunsIters:  make([]uint, 0, 3),
+	}
+
+	for i := range runs {
+
+		run := runs[i]
+
+		// Upstream Molly records a run's provenance in one
+		// graph. Split it at the antecedent and consequent.
+		run.PreProv = provRootedAt(provs[i], "pre")
+		run.PostProv = provRootedAt(provs[i], "post")
+
+		run.TimePreHolds = holdTimes(run.Model, run.PreProv, "pre")
+		run.TimePostHolds = holdTimes(run.Model, run.PostProv, "post")
+		run.NodesPreHolds = nodeHoldTimes(run.Model, run.PreProv, "pre")
+		run.NodesPostHolds = nodeHoldTimes(run.Model, run.PostProv, "post")
+
+		prefixProv(run.PreProv, run.Iteration, "pre")
+		prefixProv(run.PostProv, run.Iteration, "post")
+
+		// Prepare slice for recommendations.
+		run.Recommendation = make([]string, 0, 5)
+
+		m.addRun(run)
+	}
+
+	m.Fingerprint = hex.EncodeToString(fingerprint.Sum(nil))
+
+	return nil
+}
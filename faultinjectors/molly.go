@@ -1,201 +1,262 @@
 package faultinjectors
 
 import (
+	"bufio"
 	"fmt"
+	"hash"
+	"io"
+	"os"
 	"regexp"
 
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io/ioutil"
-	"path/filepath"
+)
+
+// Variables.
+
+var (
+	clkTimeWildRegex = regexp.MustCompile(`, ([\d]+), __WILDCARD__\)`)
+	clkTimeTwoRegex  = regexp.MustCompile(`, ([\d]+), ([\d]+)\)`)
 )
 
 // Functions.
 
-// LoadOutput
-func (m *Molly) LoadOutput() error {
+// fixClockTime places a clock goal at its send time,
+// which Molly only records in the goal's label.
+func fixClockTime(goal *Goal) {
 
-	// Find out how many iterations the fault injection run contains.
-	rawRunsCont, err := ioutil.ReadFile(filepath.Join(m.OutputDir, "runs.json"))
-	if err != nil {
-		return fmt.Errorf("Could not read runs.json file in faultInjOut directory: %v", err)
+	if goal.Table != "clock" {
+		return
 	}
 
-	// Read runs.json file into structure defined above.
-	err = json.Unmarshal(rawRunsCont, &m.Runs)
-	if err != nil {
-		return fmt.Errorf("Failed to unmarshal JSON content to runs structure: %v\n", err)
+	clkTimeWildMatches := clkTimeWildRegex.FindStringSubmatch(goal.Label)
+	clkTimeTwoMatches := clkTimeTwoRegex.FindStringSubmatch(goal.Label)
+
+	if len(clkTimeWildMatches) > 0 {
+		goal.Time = clkTimeWildMatches[1]
 	}
 
-	m.RunsIters = make([]uint, len(m.Runs))
-	m.SuccessRunsIters = make([]uint, 0, len(m.Runs))
-	m.FailedRunsIters = make([]uint, 0, 3)
+	if len(clkTimeTwoMatches) > 0 {
+		goal.Time = clkTimeTwoMatches[1]
+	}
+}
 
-	// Load antecedent and consequent provenance for each iteration.
-	for i := range m.Runs {
+// provName returns the name of the provenance
+// file of specified run and condition.
+func provName(iteration uint, condition string) string {
+	return fmt.Sprintf("run_%d_%s_provenance.json", iteration, condition)
+}
 
-		// Create lookup map for when the
-		// antecedent holds in this run.
-		m.Runs[i].TimePreHolds = make(map[string]bool)
-		for _, table := range m.Runs[i].Model.Tables["pre"] {
-			m.Runs[i].TimePreHolds[table[(len(table)-1)]] = true
-		}
+// scanProv reads provenance file name once to fingerprint
+// and validate it, holding only one element and the IDs
+// seen so far in memory.
+func (m *Molly) scanProv(name string, fingerprint hash.Hash, problems *Problems) {
 
-		// Create lookup map for when the
-		// consequent holds in this run.
-		m.Runs[i].TimePostHolds = make(map[string]bool)
-		for _, table := range m.Runs[i].Model.Tables["post"] {
-			m.Runs[i].TimePostHolds[table[(len(table)-1)]] = true
-		}
+	provFile := m.fsys.Path(name)
 
-		// Note return status of fault injection
-		// run in separate structure.
-		m.RunsIters[i] = m.Runs[i].Iteration
-		if m.Runs[i].Status == "success" {
-			m.SuccessRunsIters = append(m.SuccessRunsIters, m.Runs[i].Iteration)
-		} else {
-			m.FailedRunsIters = append(m.FailedRunsIters, m.Runs[i].Iteration)
-		}
+	f, err := m.fsys.Open(name)
+	if os.IsNotExist(err) {
+		problems.add(provFile, "", "provenance file is missing")
+		return
+	} else if err != nil {
+		problems.add(provFile, "", "failed reading provenance: %v", err)
+		return
+	}
+	defer f.Close()
 
-		preProvFile := filepath.Join(m.OutputDir, fmt.Sprintf("run_%d_pre_provenance.json", i))
-		postProvFile := filepath.Join(m.OutputDir, fmt.Sprintf("run_%d_post_provenance.json", i))
+	fingerprint.Write([]byte(fmt.Sprintf("\n%s\n", name)))
+	r := io.TeeReader(bufio.NewReader(f), fingerprint)
 
-		rawPreProvCont, err := ioutil.ReadFile(preProvFile)
-		if err != nil {
-			return fmt.Errorf("Failed reading antecedent provenance of file '%v': %v", preProvFile, err)
-		}
+	v := newProvValidator(problems, provFile, "$")
 
-		err = json.Unmarshal(rawPreProvCont, &m.Runs[i].PreProv)
-		if err != nil {
-			return fmt.Errorf("Failed to unmarshal JSON antecedent provenance data: %v\n", err)
-		}
+	err = decodeProvStream(r, v)
+	if err != nil {
+		problems.addJSONError(provFile, nil, err)
+		return
+	}
 
-		for j := range m.Runs[i].PreProv.Goals {
+	v.finish()
 
-			if m.Runs[i].PreProv.Goals[j].Table == "clock" {
+	// Fingerprint whatever follows the graph as well.
+	_, err = io.Copy(ioutil.Discard, r)
+	if err != nil {
+		problems.add(provFile, "", "failed reading provenance: %v", err)
+	}
+}
 
-				clkTimeWildRegex := regexp.MustCompile(`, ([\d]+), __WILDCARD__\)`)
-				clkTimeWildMatches := clkTimeWildRegex.FindStringSubmatch(m.Runs[i].PreProv.Goals[j].Label)
+// LoadOutput reads runs.json and the antecedent and
+// consequent provenance of each run from the output
+// directory of our Molly fork, or an archive of it.
+// All inconsistencies are collected and returned as
+// Problems. In streaming
+// mode, provenance files are only validated here and
+// later read anew by StreamProv.
+func (m *Molly) LoadOutput() error {
 
-				clkTimeTwoRegex := regexp.MustCompile(`, ([\d]+), ([\d]+)\)`)
-				clkTimeTwoMatches := clkTimeTwoRegex.FindStringSubmatch(m.Runs[i].PreProv.Goals[j].Label)
+	fsys, err := OpenFS(m.OutputDir)
+	if err != nil {
+		return fmt.Errorf("Could not open faultInjOut directory: %v", err)
+	}
+	m.fsys = fsys
 
-				if len(clkTimeWildMatches) > 0 {
-					m.Runs[i].PreProv.Goals[j].Time = clkTimeWildMatches[1]
-				}
+	// Find out how many iterations the fault injection run contains.
+	runsFile := fsys.Path("runs.json")
+	rawRunsCont, err := ReadFile(fsys, "runs.json")
+	if err != nil {
+		return fmt.Errorf("Could not read runs.json file in faultInjOut directory: %v", err)
+	}
 
-				if len(clkTimeTwoMatches) > 0 {
-					m.Runs[i].PreProv.Goals[j].Time = clkTimeTwoMatches[1]
-				}
-			}
+	// Fingerprint all inputs in the order we read them.
+	fingerprint := sha256.New()
+	fingerprint.Write([]byte("runs.json\n"))
+	fingerprint.Write(rawRunsCont)
 
-			// Prefix goals with "pre_".
-			m.Runs[i].PreProv.Goals[j].ID = fmt.Sprintf("run_%d_pre_%s", m.Runs[i].Iteration, m.Runs[i].PreProv.Goals[j].ID)
+	problems := Problems{}
 
-			// Tentative mark as antecedent not yet achieved
-			// until we can do graph operations on this provenance.
-			m.Runs[i].PreProv.Goals[j].CondHolds = false
-		}
+	// Read runs.json file into structure defined above.
+	runs := make([]*Run, 0, 10)
+	err = json.Unmarshal(rawRunsCont, &runs)
+	if err != nil {
+		problems.addJSONError(runsFile, rawRunsCont, err)
+		return problems
+	}
 
-		// Prefix rules with "pre_".
-		for j := range m.Runs[i].PreProv.Rules {
-			m.Runs[i].PreProv.Rules[j].ID = fmt.Sprintf("run_%d_pre_%s", m.Runs[i].Iteration, m.Runs[i].PreProv.Rules[j].ID)
-		}
+	if len(runs) == 0 {
+		problems.add(runsFile, "$", "contains no runs")
+	}
 
-		// Prefix edges with "pre_".
-		for j := range m.Runs[i].PreProv.Edges {
-			m.Runs[i].PreProv.Edges[j].From = fmt.Sprintf("run_%d_pre_%s", m.Runs[i].Iteration, m.Runs[i].PreProv.Edges[j].From)
-			m.Runs[i].PreProv.Edges[j].To = fmt.Sprintf("run_%d_pre_%s", m.Runs[i].Iteration, m.Runs[i].PreProv.Edges[j].To)
-		}
+	for i := range runs {
 
-		rawPostProvCont, err := ioutil.ReadFile(postProvFile)
-		if err != nil {
-			return fmt.Errorf("Failed reading consequent provenance of file '%v': %v", postProvFile, err)
+		path := fmt.Sprintf("$[%d]", i)
+		problems.validateRun(runsFile, path, i, runs[i])
+
+		if (runs[i] != nil) && (runs[i].Model == nil) {
+			problems.add(runsFile, (path + ".model"), "model is missing")
 		}
+	}
 
-		err = json.Unmarshal(rawPostProvCont, &m.Runs[i].PostProv)
-		if err != nil {
-			return fmt.Errorf("Failed to unmarshal JSON consequent provenance data: %v\n", err)
+	// Load antecedent and consequent provenance for each iteration.
+	for i := range runs {
+
+		if runs[i] == nil {
+			continue
 		}
 
-		for j := range m.Runs[i].PostProv.Goals {
+		for _, cond := range []string{"pre", "post"} {
 
-			if m.Runs[i].PostProv.Goals[j].Table == "clock" {
+			name := provName(runs[i].Iteration, cond)
+			provFile := fsys.Path(name)
 
-				clkTimeWildRegex := regexp.MustCompile(`, ([\d]+), __WILDCARD__\)`)
-				clkTimeWildMatches := clkTimeWildRegex.FindStringSubmatch(m.Runs[i].PostProv.Goals[j].Label)
+			if m.Stream {
+				m.scanProv(name, fingerprint, &problems)
+				continue
+			}
 
-				clkTimeTwoRegex := regexp.MustCompile(`, ([\d]+), ([\d]+)\)`)
-				clkTimeTwoMatches := clkTimeTwoRegex.FindStringSubmatch(m.Runs[i].PostProv.Goals[j].Label)
+			rawProvCont, err := ReadFile(fsys, name)
+			if os.IsNotExist(err) {
+				problems.add(provFile, "", "provenance file is missing")
+				continue
+			} else if err != nil {
+				problems.add(provFile, "", "failed reading provenance: %v", err)
+				continue
+			}
 
-				if len(clkTimeWildMatches) > 0 {
-					m.Runs[i].PostProv.Goals[j].Time = clkTimeWildMatches[1]
-				}
+			fingerprint.Write([]byte(fmt.Sprintf("\n%s\n", name)))
+			fingerprint.Write(rawProvCont)
 
-				if len(clkTimeTwoMatches) > 0 {
-					m.Runs[i].PostProv.Goals[j].Time = clkTimeTwoMatches[1]
-				}
+			var provData *ProvData
+			err = json.Unmarshal(rawProvCont, &provData)
+			if err != nil {
+				problems.addJSONError(provFile, rawProvCont, err)
+				continue
 			}
 
-			// Prefix goals with "post_".
-			m.Runs[i].PostProv.Goals[j].ID = fmt.Sprintf("run_%d_post_%s", m.Runs[i].Iteration, m.Runs[i].PostProv.Goals[j].ID)
+			problems.validateProv(provFile, "$", provData)
 
-			// Tentative mark as consequent not yet achieved
-			// until we can do graph operations on this provenance.
-			m.Runs[i].PostProv.Goals[j].CondHolds = false
+			if cond == "pre" {
+				runs[i].PreProv = provData
+			} else {
+				runs[i].PostProv = provData
+			}
 		}
+	}
 
-		// Prefix rules with "post_".
-		for j := range m.Runs[i].PostProv.Rules {
-			m.Runs[i].PostProv.Rules[j].ID = fmt.Sprintf("run_%d_post_%s", m.Runs[i].Iteration, m.Runs[i].PostProv.Rules[j].ID)
-		}
+	err = problems.err()
+	if err != nil {
+		return err
+	}
+
+	m.Output = Output{
+		Runs:             make([]*Run, 0, len(runs)),
+		RunsIters:        make([]uint, 0, len(runs)),
+		SuccessRunsIters: make([]uint, 0, len(runs)),
+		FailedRunsIters:  make([]uint, 0, 3),
+	}
+
+	for i := range runs {
+
+		run := runs[i]
 
-		// Prefix edges with "post_".
-		for j := range m.Runs[i].PostProv.Edges {
-			m.Runs[i].PostProv.Edges[j].From = fmt.Sprintf("run_%d_post_%s", m.Runs[i].Iteration, m.Runs[i].PostProv.Edges[j].From)
-			m.Runs[i].PostProv.Edges[j].To = fmt.Sprintf("run_%d_post_%s", m.Runs[i].Iteration, m.Runs[i].PostProv.Edges[j].To)
+		// Note when antecedent and consequent hold in this run.
+		run.TimePreHolds = holdTimes(run.Model, run.PreProv, "pre")
+		run.TimePostHolds = holdTimes(run.Model, run.PostProv, "post")
+		run.NodesPreHolds = nodeHoldTimes(run.Model, run.PreProv, "pre")
+		run.NodesPostHolds = nodeHoldTimes(run.Model, run.PostProv, "post")
+
+		if !m.Stream {
+
+			for _, provData := range []*ProvData{run.PreProv, run.PostProv} {
+				for j := range provData.Goals {
+					fixClockTime(&provData.Goals[j])
+				}
+			}
+
+			// Prefix all IDs with run and condition, and tentatively
+			// mark conditions as not yet achieved until we can do
+			// graph operations on this provenance.
+			prefixProv(run.PreProv, run.Iteration, "pre")
+			prefixProv(run.PostProv, run.Iteration, "post")
 		}
 
 		// Prepare slice for recommendations.
-		m.Runs[i].Recommendation = make([]string, 0, 5)
+		run.Recommendation = make([]string, 0, 5)
+
+		m.addRun(run)
 	}
 
-	return nil
-}
+	m.Fingerprint = hex.EncodeToString(fingerprint.Sum(nil))
 
-// GetFailureSpec returns the failure specification of this analysis.
-func (m *Molly) GetFailureSpec() *FailureSpec {
-	return m.Runs[0].FailureSpec
+	return nil
 }
 
-// GetMsgsFailedRuns returns the messages sent from all failed runs.
-func (m *Molly) GetMsgsFailedRuns() [][]*Message {
+// StreamProv passes the provenance of specified run and
+// condition to sink. In streaming mode, it decodes the
+// provenance file anew, one element at a time.
+func (m *Molly) StreamProv(iteration uint, condition string, sink ProvSink) error {
 
-	msgs := make([][]*Message, len(m.FailedRunsIters))
-	for i := range m.FailedRunsIters {
-		msgs[i] = make([]*Message, len(m.Runs[m.FailedRunsIters[i]].Messages))
-		msgs[i] = m.Runs[m.FailedRunsIters[i]].Messages
+	if !m.Stream {
+		return m.Output.StreamProv(iteration, condition, sink)
 	}
 
-	return msgs
-}
+	name := provName(iteration, condition)
+	provFile := m.fsys.Path(name)
 
-// GetOutput returns all parsed runs from Molly.
-func (m *Molly) GetOutput() []*Run {
-	return m.Runs
-}
-
-// GetRunsIters returns the iteration numbers
-// of all runs known in this struct.
-func (m *Molly) GetRunsIters() []uint {
-	return m.RunsIters
-}
+	f, err := m.fsys.Open(name)
+	if err != nil {
+		return fmt.Errorf("Failed reading provenance of file '%v': %v", provFile, err)
+	}
+	defer f.Close()
 
-// GetSuccessRunsIters returns indexes of successful runs.
-func (m *Molly) GetSuccessRunsIters() []uint {
-	return m.SuccessRunsIters
-}
+	err = decodeProvStream(bufio.NewReader(f), &prefixSink{
+		prefix: provPrefix(iteration, condition),
+		clock:  true,
+		next:   sink,
+	})
+	if err != nil {
+		return fmt.Errorf("Failed streaming provenance of file '%v': %v", provFile, err)
+	}
 
-// GetFailedRunsIters returns indexes of failed runs.
-func (m *Molly) GetFailedRunsIters() []uint {
-	return m.FailedRunsIters
+	return nil
 }
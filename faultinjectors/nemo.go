@@ -0,0 +1,250 @@
+package faultinjectors
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+)
+
+// Constants.
+
+const (
+	// NemoFormat is the value of the format field
+	// of all Nemo-native input documents.
+	NemoFormat = "nemo"
+
+	// NemoFormatVersion is the newest version of the
+	// Nemo-native input format this loader understands.
+	NemoFormatVersion = 1
+)
+
+// Functions.
+
+// NemoInputFile resolves name in fsys to the Nemo-native
+// input file: name itself, or nemo.json or nemo.ndjson
+// inside name if it is a directory.
+func NemoInputFile(fsys FS, name string) (string, error) {
+
+	info, err := fsys.Stat(name)
+	if err != nil {
+		return "", err
+	}
+
+	if !info.IsDir() {
+		return name, nil
+	}
+
+	for _, file := range []string{"nemo.json", "nemo.ndjson"} {
+
+		file = path.Join(name, file)
+		if _, err := fsys.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+
+	return "", fmt.Errorf("Directory '%s' contains neither nemo.json nor nemo.ndjson", fsys.Path(name))
+}
+
+// isNDJSON decides by file extension whether file
+// holds newline-delimited JSON.
+func isNDJSON(file string) bool {
+
+	ext := strings.ToLower(filepath.Ext(file))
+
+	return (ext == ".ndjson") || (ext == ".jsonl")
+}
+
+// checkHeader verifies that header announces a
+// version of the Nemo-native format we understand.
+func checkHeader(header *NemoHeader) error {
+
+	if header.Format != NemoFormat {
+		return fmt.Errorf("Unknown input format '%s', expected '%s'", header.Format, NemoFormat)
+	}
+
+	if (header.Version < 1) || (header.Version > NemoFormatVersion) {
+		return fmt.Errorf("Unsupported version %d of Nemo input format, this Nemo reads versions 1 to %d", header.Version, NemoFormatVersion)
+	}
+
+	return nil
+}
+
+// decodeNDJSON reads the header line and all
+// following run lines of an NDJSON document.
+func decodeNDJSON(r io.Reader) ([]*Run, error) {
+
+	dec := json.NewDecoder(r)
+
+	header := &NemoHeader{}
+	err := dec.Decode(header)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]*Run, 0, 10)
+
+	for {
+
+		run := &Run{}
+
+		err := dec.Decode(run)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// decodeJSON reads a single-object JSON document.
+func decodeJSON(content []byte) ([]*Run, error) {
+
+	doc := &NemoDocument{}
+
+	err := json.Unmarshal(content, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkHeader(&doc.NemoHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.Runs, nil
+}
+
+// LoadOutput reads all runs from the Nemo-native
+// input file, which may also reside in an archive,
+// and prepares them for analysis.
+func (n *Nemo) LoadOutput() error {
+
+	fsys, name, err := openInput(n.InputPath)
+	if err != nil {
+		return fmt.Errorf("Could not open Nemo input: %v", err)
+	}
+
+	name, err = NemoInputFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("Could not find Nemo input: %v", err)
+	}
+	file := fsys.Path(name)
+
+	content, err := ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("Could not read Nemo input file '%s': %v", file, err)
+	}
+
+	var runs []*Run
+	if isNDJSON(file) {
+		runs, err = decodeNDJSON(bytes.NewReader(content))
+	} else {
+		runs, err = decodeJSON(content)
+	}
+	problems := Problems{}
+
+	if err != nil {
+		problems.addJSONError(file, content, err)
+		return problems
+	}
+
+	if len(runs) == 0 {
+		problems.add(file, "$", "contains no runs")
+	}
+
+	for i := range runs {
+
+		// Locate runs by JSON path, or by line in NDJSON.
+		path := fmt.Sprintf("$.runs[%d]", i)
+		if isNDJSON(file) {
+			path = fmt.Sprintf("line %d: $", (i + 2))
+		}
+
+		problems.validateRun(file, path, i, runs[i])
+
+		if runs[i] == nil {
+			continue
+		}
+
+		if runs[i].PreProv != nil {
+			problems.validateProv(file, (path + ".preProv"), runs[i].PreProv)
+		}
+
+		if runs[i].PostProv != nil {
+			problems.validateProv(file, (path + ".postProv"), runs[i].PostProv)
+		}
+	}
+
+	err = problems.err()
+	if err != nil {
+		return err
+	}
+
+	n.Output = Output{
+		Runs:             make([]*Run, 0, len(runs)),
+		RunsIters:        make([]uint, 0, len(runs)),
+		SuccessRunsIters: make([]uint, 0, len(runs)),
+		FailedRunsIters:  make([]uint, 0, 3),
+	}
+
+	for i := range runs {
+
+		run := runs[i]
+
+		if run.PreProv == nil {
+			run.PreProv = &ProvData{}
+		}
+
+		if run.PostProv == nil {
+			run.PostProv = &ProvData{}
+		}
+
+		run.TimePreHolds = holdTimes(run.Model, run.PreProv, "pre")
+		run.TimePostHolds = holdTimes(run.Model, run.PostProv, "post")
+		run.NodesPreHolds = nodeHoldTimes(run.Model, run.PreProv, "pre")
+		run.NodesPostHolds = nodeHoldTimes(run.Model, run.PostProv, "post")
+
+		prefixProv(run.PreProv, run.Iteration, "pre")
+		prefixProv(run.PostProv, run.Iteration, "post")
+
+		// Drop any analysis results present in the input.
+		run.Recommendation = make([]string, 0, 5)
+		run.Corrections = nil
+		run.Pairing = nil
+		run.Alignment = nil
+		run.MissingEvents = nil
+		run.ExcessEvents = nil
+		run.RootCauses = nil
+		run.FailureCluster = nil
+		run.MessageDiff = nil
+		run.HazardWindows = nil
+		run.InterProto = nil
+		run.InterProtoMissing = nil
+		run.UnionProto = nil
+		run.UnionProtoMissing = nil
+		run.Suspiciousness = nil
+
+		n.addRun(run)
+	}
+
+	fingerprint := sha256.Sum256(content)
+	n.Fingerprint = hex.EncodeToString(fingerprint[:])
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package faultinjectors
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"path/filepath"
+)
+
+// Runs.
+
+// nemoSuccessRun broadcasts from a to b, which
+// logs the broadcast and thereby satisfies post.
+const nemoSuccessRun = `{"iteration": 0, "status": "success",
+ "failureSpec": {"eot": 3, "eff": 2, "maxCrashes": 0, "nodes": ["a", "b"], "crashes": [], "omissions": []},
+ "messages": [{"table": "bcast", "from": "a", "to": "b", "sendTime": 1, "receiveTime": 2}],
+ "preProv": {"goals": [{"id": "g0", "label": "pre(a, 1)", "table": "pre", "time": "1"}], "rules": [], "edges": []},
+ "postProv": {"goals": [{"id": "g0", "label": "post(b, 3)", "table": "post", "time": "3"}, {"id": "g1", "label": "log(b, 2)", "table": "log", "time": "2"}],
+  "rules": [{"id": "r0", "label": "post", "table": "post", "type": ""}],
+  "edges": [{"from": "g0", "to": "r0"}, {"from": "r0", "to": "g1"}]}}`
+
+// nemoFailedRun loses the broadcast from a to b.
+const nemoFailedRun = `{"iteration": 1, "status": "failure",
+ "failureSpec": {"eot": 3, "eff": 2, "maxCrashes": 0, "nodes": ["a", "b"], "crashes": [], "omissions": [{"from": "a", "to": "b", "time": 1}]},
+ "preProv": {"goals": [{"id": "g0", "label": "pre(a, 1)", "table": "pre", "time": "1"}], "rules": [], "edges": []},
+ "postProv": {"goals": [], "rules": [], "edges": []}}`
+
+// nemoBrokenRun has an unknown status, a message from
+// an unknown node, and an edge to a missing rule.
+const nemoBrokenRun = `{"iteration": 1, "status": "failed",
+ "failureSpec": {"eot": 3, "eff": 2, "maxCrashes": 0, "nodes": ["a", "b"], "crashes": [], "omissions": []},
+ "messages": [{"table": "bcast", "from": "c", "to": "b", "sendTime": 1, "receiveTime": 2}],
+ "postProv": {"goals": [{"id": "g0", "label": "post(b, 3)", "table": "post", "time": "3"}], "rules": [], "edges": [{"from": "g0", "to": "r0"}]}}`
+
+// Functions.
+
+// oneLine joins the lines of a run into one NDJSON line.
+func oneLine(run string) string {
+	return strings.Replace(run, "\n", "", -1)
+}
+
+func TestNemoLoadOutput(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		success  []uint
+		failed   []uint
+		problems []string
+	}{
+		{
+			name:    "JSON",
+			file:    "nemo.json",
+			content: `{"format": "nemo", "version": 1, "runs": [` + nemoSuccessRun + `, ` + nemoFailedRun + `]}`,
+			success: []uint{0},
+			failed:  []uint{1},
+		},
+		{
+			name:    "NDJSON",
+			file:    "nemo.ndjson",
+			content: "{\"format\": \"nemo\", \"version\": 1}\n" + oneLine(nemoSuccessRun) + "\n" + oneLine(nemoFailedRun) + "\n",
+			success: []uint{0},
+			failed:  []uint{1},
+		},
+		{
+			name:    "malformed JSON",
+			file:    "nemo.json",
+			content: `{"format": "nemo", "version": 1, "runs": [` + nemoSuccessRun + `, ` + nemoBrokenRun + `]}`,
+			problems: []string{
+				"$.runs[1].status",
+				"$.runs[1].messages[0].from",
+				"$.runs[1].postProv.edges[0].to",
+			},
+		},
+		{
+			name:    "malformed NDJSON",
+			file:    "nemo.ndjson",
+			content: "{\"format\": \"nemo\", \"version\": 1}\n" + oneLine(nemoSuccessRun) + "\n" + oneLine(nemoBrokenRun) + "\n",
+			problems: []string{
+				"line 3: $.status",
+				"line 3: $.messages[0].from",
+				"line 3: $.postProv.edges[0].to",
+			},
+		},
+		{
+			name:     "newer version",
+			file:     "nemo.json",
+			content:  `{"format": "nemo", "version": 2, "runs": []}`,
+			problems: []string{""},
+		},
+	}
+
+	for _, tt := range tests {
+
+		dir, err := ioutil.TempDir("", "nemo-input")
+		if err != nil {
+			t.Fatalf("%s: creating directory failed: %v", tt.name, err)
+		}
+		defer os.RemoveAll(dir)
+
+		err = ioutil.WriteFile(filepath.Join(dir, tt.file), []byte(tt.content), 0644)
+		if err != nil {
+			t.Fatalf("%s: writing input failed: %v", tt.name, err)
+		}
+
+		n := &Nemo{InputPath: dir}
+		err = n.LoadOutput()
+
+		if tt.problems != nil {
+
+			problems, ok := err.(Problems)
+			if !ok {
+				t.Errorf("%s: loading returned %v, expected problems", tt.name, err)
+				continue
+			}
+
+			paths := make([]string, len(problems))
+			for i := range problems {
+				paths[i] = problems[i].Path
+			}
+
+			if !reflect.DeepEqual(paths, tt.problems) {
+				t.Errorf("%s: problems at %v, expected at %v", tt.name, paths, tt.problems)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: loading failed: %v", tt.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(n.SuccessRunsIters, tt.success) || !reflect.DeepEqual(n.FailedRunsIters, tt.failed) {
+			t.Errorf("%s: successful runs %v and failed runs %v, expected %v and %v", tt.name, n.SuccessRunsIters, n.FailedRunsIters, tt.success, tt.failed)
+		}
+
+		if !n.Runs[0].TimePostHolds["3"] {
+			t.Errorf("%s: consequent does not hold at 3 in run 0", tt.name)
+		}
+	}
+}
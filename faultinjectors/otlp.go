@@ -0,0 +1,604 @@
+package faultinjectors
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+)
+
+// Constants.
+
+const (
+	// DefaultTraceFailure labels traces containing
+	// a span with error status as failed.
+	DefaultTraceFailure = "status=error"
+)
+
+// Structs.
+
+// otlpSpan is a span together with the service that
+// emitted it and its place in the trace.
+type otlpSpan struct {
+	*OTLPSpan
+	service  string
+	start    uint64
+	end      uint64
+	children []*otlpSpan
+	args     []string
+}
+
+// otlpTrace collects all spans sharing a trace ID.
+type otlpTrace struct {
+	id    string
+	start uint64
+	spans []*otlpSpan
+}
+
+// spanClause compares one span field to a value.
+type spanClause struct {
+	field  string
+	negate bool
+	value  string
+}
+
+// Functions.
+
+// otlpInputFiles lists the OTLP JSON files in fsys to
+// read: name itself, or all .json, .jsonl, and .ndjson
+// files in name if it is a directory.
+func otlpInputFiles(fsys FS, name string) ([]string, error) {
+
+	info, err := fsys.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{name}, nil
+	}
+
+	entries, err := fsys.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(entries))
+	for _, entry := range entries {
+
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && ((ext == ".json") || isNDJSON(entry.Name())) {
+			files = append(files, path.Join(name, entry.Name()))
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("Directory '%s' contains no OTLP JSON files", fsys.Path(name))
+	}
+
+	return files, nil
+}
+
+// attrString renders an attribute value as string.
+func attrString(v OTLPAnyValue) string {
+
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return fmt.Sprintf("%t", *v.BoolValue)
+	case v.IntValue != nil:
+		return v.IntValue.String()
+	case v.DoubleValue != nil:
+		return v.DoubleValue.String()
+	}
+
+	return ""
+}
+
+// attr looks up attribute key.
+func attr(attrs []OTLPKeyValue, key string) (string, bool) {
+
+	for i := range attrs {
+		if attrs[i].Key == key {
+			return attrString(attrs[i].Value), true
+		}
+	}
+
+	return "", false
+}
+
+// statusName normalizes a span's status code to
+// "unset", "ok", or "error".
+func statusName(status OTLPStatus) string {
+
+	switch code := status.Code.(type) {
+	case float64:
+		switch code {
+		case 1:
+			return "ok"
+		case 2:
+			return "error"
+		}
+	case string:
+		switch strings.ToUpper(code) {
+		case "1", "STATUS_CODE_OK", "OK":
+			return "ok"
+		case "2", "STATUS_CODE_ERROR", "ERROR":
+			return "error"
+		}
+	}
+
+	return "unset"
+}
+
+// parseTraceFailure parses the predicate labelling
+// traces as failed. A predicate is a disjunction
+// (||) of conjunctions (&&) of clauses field=value
+// or field!=value over the fields status, name,
+// service, and attr.<key>. A trace fails if any of
+// its spans satisfies the predicate.
+func parseTraceFailure(expr string) ([][]spanClause, error) {
+
+	alts := strings.Split(expr, "||")
+	pred := make([][]spanClause, 0, len(alts))
+
+	for _, alt := range alts {
+
+		conj := strings.Split(alt, "&&")
+		clauses := make([]spanClause, 0, len(conj))
+
+		for _, c := range conj {
+
+			c = strings.TrimSpace(c)
+
+			clause := spanClause{}
+			parts := strings.SplitN(c, "!=", 2)
+			if len(parts) == 2 {
+				clause.negate = true
+			} else {
+				parts = strings.SplitN(c, "=", 2)
+			}
+
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("clause '%s' is neither field=value nor field!=value", c)
+			}
+
+			clause.field = strings.TrimSpace(parts[0])
+			clause.value = strings.TrimSpace(parts[1])
+
+			if (clause.field != "status") && (clause.field != "name") &&
+				(clause.field != "service") && !strings.HasPrefix(clause.field, "attr.") {
+				return nil, fmt.Errorf("unknown field '%s' in clause '%s'", clause.field, c)
+			}
+
+			clauses = append(clauses, clause)
+		}
+
+		pred = append(pred, clauses)
+	}
+
+	return pred, nil
+}
+
+// matches reports whether span satisfies clause.
+func (clause spanClause) matches(span *otlpSpan) bool {
+
+	var actual string
+	found := true
+
+	switch {
+	case clause.field == "status":
+		actual = statusName(span.Status)
+	case clause.field == "name":
+		actual = span.Name
+	case clause.field == "service":
+		actual = span.service
+	default:
+		actual, found = attr(span.Attributes, strings.TrimPrefix(clause.field, "attr."))
+	}
+
+	equal := found && (actual == clause.value)
+
+	return equal != clause.negate
+}
+
+// traceFailed evaluates the failure predicate on trace.
+func traceFailed(pred [][]spanClause, trace *otlpTrace) bool {
+
+	for _, span := range trace.spans {
+		for _, clauses := range pred {
+
+			all := true
+			for _, clause := range clauses {
+				if !clause.matches(span) {
+					all = false
+					break
+				}
+			}
+
+			if all {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// readOTLPExports decodes all exports in content,
+// be it one JSON document or one per line.
+func readOTLPExports(content []byte) ([]*OTLPExport, error) {
+
+	exports := make([]*OTLPExport, 0, 1)
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	for {
+
+		export := &OTLPExport{}
+
+		err := dec.Decode(export)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		exports = append(exports, export)
+	}
+
+	return exports, nil
+}
+
+// groupTraces sorts all spans of exports into traces,
+// ordered by their first span's start.
+func groupTraces(exports []*OTLPExport) ([]*otlpTrace, error) {
+
+	traces := make(map[string]*otlpTrace)
+
+	for _, export := range exports {
+		for _, rs := range export.ResourceSpans {
+
+			service, found := attr(rs.Resource.Attributes, "service.name")
+			if !found || (service == "") {
+				service = "unknown_service"
+			}
+
+			scopes := append(rs.ScopeSpans, rs.InstrumentationLibrarySpans...)
+			for _, scope := range scopes {
+				for _, s := range scope.Spans {
+
+					start, err := s.StartTimeUnixNano.Int64()
+					if err != nil {
+						return nil, fmt.Errorf("span %s: invalid start time: %v", s.SpanID, err)
+					}
+
+					end, err := s.EndTimeUnixNano.Int64()
+					if err != nil {
+						return nil, fmt.Errorf("span %s: invalid end time: %v", s.SpanID, err)
+					}
+
+					span := &otlpSpan{
+						OTLPSpan: s,
+						service:  service,
+						start:    uint64(start),
+						end:      uint64(end),
+					}
+
+					trace, found := traces[s.TraceID]
+					if !found {
+						trace = &otlpTrace{
+							id:    s.TraceID,
+							start: span.start,
+						}
+						traces[s.TraceID] = trace
+					}
+
+					if span.start < trace.start {
+						trace.start = span.start
+					}
+
+					trace.spans = append(trace.spans, span)
+				}
+			}
+		}
+	}
+
+	sorted := make([]*otlpTrace, 0, len(traces))
+	for _, trace := range traces {
+		sorted = append(sorted, trace)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+
+		if sorted[i].start != sorted[j].start {
+			return sorted[i].start < sorted[j].start
+		}
+
+		return sorted[i].id < sorted[j].id
+	})
+
+	return sorted, nil
+}
+
+// otlpRun maps one trace onto a run. Each distinct span
+// start or end timestamp is one time step. Services are
+// nodes, and a span's invocation by and response to a
+// parent span in another service are messages. The
+// antecedent holds once a root span was invoked, each
+// span's invocation depending on its parent's. The
+// consequent holds once a root span completed without
+// error in a successful trace, each completion depending
+// on the completions of its children and linked spans.
+func otlpRun(iteration uint, failed bool, trace *otlpTrace) *Run {
+
+	// Order spans by start, ties broken by span ID.
+	sort.Slice(trace.spans, func(i, j int) bool {
+
+		if trace.spans[i].start != trace.spans[j].start {
+			return trace.spans[i].start < trace.spans[j].start
+		}
+
+		return trace.spans[i].SpanID < trace.spans[j].SpanID
+	})
+
+	stamps := make(map[uint64]bool)
+	byID := make(map[string]*otlpSpan)
+	services := make(map[string]bool)
+	occurrences := make(map[string]int)
+
+	for _, span := range trace.spans {
+
+		stamps[span.start] = true
+		stamps[span.end] = true
+		byID[span.SpanID] = span
+		services[span.service] = true
+
+		// Identify spans across traces by service, name,
+		// and how often the name occurred before.
+		key := span.service + "\x00" + span.Name
+		span.args = []string{span.service, fmt.Sprintf("%d", occurrences[key])}
+		occurrences[key]++
+	}
+
+	sortedStamps := make([]uint64, 0, len(stamps))
+	for stamp := range stamps {
+		sortedStamps = append(sortedStamps, stamp)
+	}
+	sort.Slice(sortedStamps, func(i, j int) bool {
+		return sortedStamps[i] < sortedStamps[j]
+	})
+
+	steps := make(map[uint64]uint, len(sortedStamps))
+	for i, stamp := range sortedStamps {
+		steps[stamp] = uint(i + 1)
+	}
+
+	roots := make([]*otlpSpan, 0, 1)
+	for _, span := range trace.spans {
+
+		parent, found := byID[span.ParentSpanID]
+		if (span.ParentSpanID == "") || !found {
+			roots = append(roots, span)
+		} else {
+			parent.children = append(parent.children, span)
+		}
+	}
+
+	nodes := sortedNames(services)
+	status := "success"
+	if failed {
+		status = "failure"
+	}
+
+	run := &Run{
+		Iteration: iteration,
+		Status:    status,
+		FailureSpec: &FailureSpec{
+			EOT:       uint(len(sortedStamps)),
+			EFF:       0,
+			Nodes:     &nodes,
+			Crashes:   &[]CrashFailure{},
+			Omissions: &[]MessageLoss{},
+		},
+		Messages: make([]*Message, 0, len(trace.spans)),
+	}
+
+	pre := newProvBuilder()
+	post := newProvBuilder()
+
+	for _, span := range trace.spans {
+
+		parent, hasParent := byID[span.ParentSpanID]
+
+		// Invocations across services are requests
+		// and responses between nodes.
+		if hasParent && (parent.service != span.service) {
+
+			run.Messages = append(run.Messages, &Message{
+				Content:  span.Name,
+				SendNode: parent.service,
+				RecvNode: span.service,
+				SendTime: steps[span.start],
+				RecvTime: steps[span.start],
+			}, &Message{
+				Content:  span.Name,
+				SendNode: span.service,
+				RecvNode: parent.service,
+				SendTime: steps[span.end],
+				RecvTime: steps[span.end],
+			})
+		}
+
+		// Antecedent: a span is invoked by its parent.
+		call := pre.goal(span.Name, span.args, steps[span.start])
+		if hasParent {
+
+			ruleType := ""
+			if parent.service != span.service {
+				ruleType = "async"
+			}
+
+			pre.rule(span.Name, ruleType, call, pre.goal(parent.Name, parent.args, steps[parent.start]))
+		}
+	}
+
+	for _, span := range trace.spans {
+
+		// Consequent: only spans completing without
+		// error contribute.
+		if statusName(span.Status) == "error" {
+			continue
+		}
+
+		body := make([]string, 0, (len(span.children) + len(span.Links)))
+		ruleType := ""
+
+		deps := make([]*otlpSpan, 0, (len(span.children) + len(span.Links)))
+		deps = append(deps, span.children...)
+		for _, link := range span.Links {
+			if linked, found := byID[link.SpanID]; found && (link.TraceID == span.TraceID) && (linked.end <= span.end) {
+				deps = append(deps, linked)
+			}
+		}
+
+		for _, dep := range deps {
+
+			if statusName(dep.Status) == "error" {
+				continue
+			}
+
+			body = append(body, post.goal(dep.Name, dep.args, steps[dep.end]))
+			if dep.service != span.service {
+				ruleType = "async"
+			}
+		}
+
+		done := post.goal(span.Name, span.args, steps[span.end])
+		if len(body) > 0 {
+			post.rule(span.Name, ruleType, done, body...)
+		}
+	}
+
+	for _, root := range roots {
+
+		preGoal := pre.goal("pre", root.args, steps[root.start])
+		pre.rule("pre", "", preGoal, pre.goal(root.Name, root.args, steps[root.start]))
+
+		if !failed && (statusName(root.Status) != "error") {
+			postGoal := post.goal("post", root.args, steps[root.end])
+			post.rule("post", "", postGoal, post.goal(root.Name, root.args, steps[root.end]))
+		}
+	}
+
+	run.PreProv = pre.prov
+	run.PostProv = post.prov
+
+	return run
+}
+
+// LoadOutput reads OTLP JSON trace exports, possibly
+// from an archive, and turns each trace into one run,
+// labelled by the failure predicate.
+func (o *OTLP) LoadOutput() error {
+
+	expr := o.Failure
+	if expr == "" {
+		expr = DefaultTraceFailure
+	}
+
+	pred, err := parseTraceFailure(expr)
+	if err != nil {
+		return fmt.Errorf("Invalid trace failure predicate: %v", err)
+	}
+
+	fsys, name, err := openInput(o.InputPath)
+	if err != nil {
+		return fmt.Errorf("Could not open OTLP trace exports: %v", err)
+	}
+
+	files, err := otlpInputFiles(fsys, name)
+	if err != nil {
+		return fmt.Errorf("Could not find OTLP trace exports: %v", err)
+	}
+
+	fingerprint := sha256.New()
+	fingerprint.Write([]byte(expr))
+
+	exports := make([]*OTLPExport, 0, len(files))
+	for _, file := range files {
+
+		content, err := ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("Failed reading '%s': %v", fsys.Path(file), err)
+		}
+		fingerprint.Write([]byte(fmt.Sprintf("\n%s\n", path.Base(file))))
+		fingerprint.Write(content)
+
+		fileExports, err := readOTLPExports(content)
+		if err != nil {
+			return fmt.Errorf("Failed to parse OTLP JSON in '%s': %v", fsys.Path(file), err)
+		}
+
+		exports = append(exports, fileExports...)
+	}
+
+	traces, err := groupTraces(exports)
+	if err != nil {
+		return fmt.Errorf("Invalid OTLP trace export: %v", err)
+	}
+
+	if len(traces) == 0 {
+		return fmt.Errorf("No spans found in OTLP trace exports at '%s'", o.InputPath)
+	}
+
+	o.Output = Output{
+		Runs:             make([]*Run, 0, len(traces)),
+		RunsIters:        make([]uint, 0, len(traces)),
+		SuccessRunsIters: make([]uint, 0, len(traces)),
+		FailedRunsIters:  make([]uint, 0, 3),
+	}
+
+	problems := Problems{}
+
+	for i, trace := range traces {
+
+		run := otlpRun(uint(i), traceFailed(pred, trace), trace)
+
+		// Check the run the trace maps to like
+		// runs read from any other fault injector.
+		file := fmt.Sprintf("%s (trace %s)", o.InputPath, trace.id)
+		problems.validateRun(file, "$", i, run)
+		problems.validateProv(file, "$.preProv", run.PreProv)
+		problems.validateProv(file, "$.postProv", run.PostProv)
+
+		run.TimePreHolds = holdTimes(nil, run.PreProv, "pre")
+		run.TimePostHolds = holdTimes(nil, run.PostProv, "post")
+		run.NodesPreHolds = nodeHoldTimes(nil, run.PreProv, "pre")
+		run.NodesPostHolds = nodeHoldTimes(nil, run.PostProv, "post")
+
+		prefixProv(run.PreProv, run.Iteration, "pre")
+		prefixProv(run.PostProv, run.Iteration, "post")
+
+		// Prepare slice for recommendations.
+		run.Recommendation = make([]string, 0, 5)
+
+		o.addRun(run)
+	}
+
+	err = problems.err()
+	if err != nil {
+		return err
+	}
+
+	o.Fingerprint = hex.EncodeToString(fingerprint.Sum(nil))
+
+	return nil
+}
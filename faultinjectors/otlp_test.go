@@ -0,0 +1,112 @@
+package faultinjectors
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"path/filepath"
+)
+
+// Traces.
+
+// checkoutTrace renders one export of a checkout in the
+// frontend that reads the cart, whose span has status code.
+func checkoutTrace(trace int, code int) string {
+
+	return fmt.Sprintf(`{"resourceSpans": [`+
+		`{"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "frontend"}}]}, "scopeSpans": [{"spans": [`+
+		`{"traceId": "t%d", "spanId": "a%d", "name": "GET /checkout", "startTimeUnixNano": "1000", "endTimeUnixNano": "1100", "status": {"code": 0}}]}]}, `+
+		`{"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "cart"}}]}, "scopeSpans": [{"spans": [`+
+		`{"traceId": "t%d", "spanId": "b%d", "parentSpanId": "a%d", "name": "GetCart", "startTimeUnixNano": "1010", "endTimeUnixNano": "1030", "status": {"code": %d}}]}]}]}`,
+		trace, trace, trace, trace, trace, code)
+}
+
+// Functions.
+
+func TestOTLPLoadOutput(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		content  string
+		success  []uint
+		failed   []uint
+		messages int
+		err      string
+	}{
+		{
+			name:     "successful and failed trace",
+			content:  checkoutTrace(1, 1) + "\n" + checkoutTrace(2, 2) + "\n",
+			success:  []uint{0},
+			failed:   []uint{1},
+			messages: 2,
+		},
+		{
+			name:    "malformed start time",
+			content: strings.Replace(checkoutTrace(1, 1), `"1010"`, `"10.5e"`, 1),
+			err:     "Failed to parse OTLP JSON",
+		},
+		{
+			name:    "truncated export",
+			content: checkoutTrace(1, 1)[:200],
+			err:     "Failed to parse OTLP JSON",
+		},
+		{
+			name:    "no spans",
+			content: `{"resourceSpans": []}`,
+			err:     "No spans found",
+		},
+	}
+
+	for _, tt := range tests {
+
+		dir, err := ioutil.TempDir("", "nemo-otlp")
+		if err != nil {
+			t.Fatalf("%s: creating directory failed: %v", tt.name, err)
+		}
+		defer os.RemoveAll(dir)
+
+		err = ioutil.WriteFile(filepath.Join(dir, "traces.jsonl"), []byte(tt.content), 0644)
+		if err != nil {
+			t.Fatalf("%s: writing traces failed: %v", tt.name, err)
+		}
+
+		o := &OTLP{InputPath: dir}
+		err = o.LoadOutput()
+
+		if tt.err != "" {
+
+			if (err == nil) || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: loading returned %v, expected '%s'", tt.name, err, tt.err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: loading failed: %v", tt.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(o.SuccessRunsIters, tt.success) || !reflect.DeepEqual(o.FailedRunsIters, tt.failed) {
+			t.Errorf("%s: successful runs %v and failed runs %v, expected %v and %v", tt.name, o.SuccessRunsIters, o.FailedRunsIters, tt.success, tt.failed)
+		}
+
+		run := o.Runs[0]
+
+		if !reflect.DeepEqual(*run.FailureSpec.Nodes, []string{"cart", "frontend"}) {
+			t.Errorf("%s: nodes are %v, expected [cart frontend]", tt.name, *run.FailureSpec.Nodes)
+		}
+
+		if len(run.Messages) != tt.messages {
+			t.Errorf("%s: %d messages, expected %d", tt.name, len(run.Messages), tt.messages)
+		}
+
+		if !run.TimePostHolds[fmt.Sprintf("%d", run.FailureSpec.EOT)] {
+			t.Errorf("%s: consequent does not hold at end of time", tt.name)
+		}
+	}
+}
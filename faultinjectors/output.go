@@ -0,0 +1,254 @@
+package faultinjectors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Structs.
+
+// provBuilder assembles a provenance graph goal by goal
+// for fault injectors that derive provenance themselves.
+type provBuilder struct {
+	prov  *ProvData
+	goals map[string]string
+}
+
+// Functions.
+
+// provPrefix returns the prefix making IDs of the
+// specified run and condition unique.
+func provPrefix(iteration uint, condition string) string {
+	return fmt.Sprintf("run_%d_%s_", iteration, condition)
+}
+
+// prefixProv makes the IDs of all goals, rules, and
+// edges in provData unique across runs and conditions
+// and marks all goals as not (yet) achieving condition.
+func prefixProv(provData *ProvData, iteration uint, condition string) {
+
+	prefix := provPrefix(iteration, condition)
+
+	for j := range provData.Goals {
+		provData.Goals[j].ID = prefix + provData.Goals[j].ID
+		provData.Goals[j].CondHolds = false
+	}
+
+	for j := range provData.Rules {
+		provData.Rules[j].ID = prefix + provData.Rules[j].ID
+	}
+
+	for j := range provData.Edges {
+		provData.Edges[j].From = prefix + provData.Edges[j].From
+		provData.Edges[j].To = prefix + provData.Edges[j].To
+	}
+}
+
+// holdTimes collects the time steps at which condition
+// holds. The model's table for condition carries the time
+// in its last column. Without a model, we fall back to the
+// time of all provenance goals of that table.
+func holdTimes(model *Model, provData *ProvData, condition string) map[string]bool {
+
+	times := make(map[string]bool)
+
+	if (model != nil) && (model.Tables != nil) {
+
+		for _, row := range model.Tables[condition] {
+
+			if len(row) > 0 {
+				times[row[(len(row)-1)]] = true
+			}
+		}
+
+		return times
+	}
+
+	if provData == nil {
+		return times
+	}
+
+	for j := range provData.Goals {
+
+		if provData.Goals[j].Table == condition {
+			times[provData.Goals[j].Time] = true
+		}
+	}
+
+	return times
+}
+
+// goalLocation returns the first argument in the label
+// of goal, by convention the node it is located at.
+func goalLocation(goal *Goal) string {
+
+	args := goal.Label[(strings.Index(goal.Label, "(") + 1):]
+
+	end := strings.IndexAny(args, ",)")
+	if end == -1 {
+		return strings.TrimSpace(args)
+	}
+
+	return strings.TrimSpace(args[:end])
+}
+
+// nodeHoldTimes collects the time steps at which condition
+// holds, per node. The model's table for condition carries
+// the node in its first and the time in its last column.
+// Rows without a node column hold at all nodes and are
+// collected under the empty node name. Without a model,
+// we fall back to location and time of all provenance
+// goals of that table.
+func nodeHoldTimes(model *Model, provData *ProvData, condition string) map[string]map[string]bool {
+
+	times := make(map[string]map[string]bool)
+
+	hold := func(node string, time string) {
+
+		if times[node] == nil {
+			times[node] = make(map[string]bool)
+		}
+
+		times[node][time] = true
+	}
+
+	if (model != nil) && (model.Tables != nil) {
+
+		for _, row := range model.Tables[condition] {
+
+			if len(row) > 1 {
+				hold(row[0], row[(len(row)-1)])
+			} else if len(row) == 1 {
+				hold("", row[0])
+			}
+		}
+
+		return times
+	}
+
+	if provData == nil {
+		return times
+	}
+
+	for j := range provData.Goals {
+
+		if provData.Goals[j].Table == condition {
+			hold(goalLocation(&provData.Goals[j]), provData.Goals[j].Time)
+		}
+	}
+
+	return times
+}
+
+// addRun appends run to the output and notes its
+// return status in the respective structure.
+func (o *Output) addRun(run *Run) {
+
+	o.Runs = append(o.Runs, run)
+	o.RunsIters = append(o.RunsIters, run.Iteration)
+
+	if run.Status == "success" {
+		o.SuccessRunsIters = append(o.SuccessRunsIters, run.Iteration)
+	} else {
+		o.FailedRunsIters = append(o.FailedRunsIters, run.Iteration)
+	}
+}
+
+// GetFailureSpec returns the failure specification of this analysis.
+func (o *Output) GetFailureSpec() *FailureSpec {
+	return o.Runs[0].FailureSpec
+}
+
+// GetMsgsFailedRuns returns the messages sent from all failed runs.
+func (o *Output) GetMsgsFailedRuns() [][]*Message {
+
+	msgs := make([][]*Message, len(o.FailedRunsIters))
+	for i := range o.FailedRunsIters {
+		msgs[i] = make([]*Message, len(o.Runs[o.FailedRunsIters[i]].Messages))
+		msgs[i] = o.Runs[o.FailedRunsIters[i]].Messages
+	}
+
+	return msgs
+}
+
+// GetFingerprint returns a hash over the contents
+// of all files the output was loaded from.
+func (o *Output) GetFingerprint() string {
+	return o.Fingerprint
+}
+
+// GetOutput returns all parsed runs.
+func (o *Output) GetOutput() []*Run {
+	return o.Runs
+}
+
+// GetRunsIters returns the iteration numbers
+// of all runs known in this struct.
+func (o *Output) GetRunsIters() []uint {
+	return o.RunsIters
+}
+
+// GetSuccessRunsIters returns indexes of successful runs.
+func (o *Output) GetSuccessRunsIters() []uint {
+	return o.SuccessRunsIters
+}
+
+// GetFailedRunsIters returns indexes of failed runs.
+func (o *Output) GetFailedRunsIters() []uint {
+	return o.FailedRunsIters
+}
+
+// newProvBuilder starts an empty provenance graph.
+func newProvBuilder() *provBuilder {
+
+	return &provBuilder{
+		prov:  &ProvData{},
+		goals: make(map[string]string),
+	}
+}
+
+// goal returns the ID of the goal with label, creating
+// the goal if it does not exist yet.
+func (p *provBuilder) goal(table string, args []string, time uint) string {
+
+	label := fmt.Sprintf("%s(%s)", table, strings.Join(args, ", "))
+
+	if id, found := p.goals[label]; found {
+		return id
+	}
+
+	id := fmt.Sprintf("goal%d", len(p.prov.Goals))
+	p.prov.Goals = append(p.prov.Goals, Goal{
+		ID:    id,
+		Label: label,
+		Table: table,
+		Time:  fmt.Sprintf("%d", time),
+	})
+	p.goals[label] = id
+
+	return id
+}
+
+// rule adds a rule deriving head from body.
+func (p *provBuilder) rule(table string, ruleType string, head string, body ...string) {
+
+	id := fmt.Sprintf("rule%d", len(p.prov.Rules))
+	p.prov.Rules = append(p.prov.Rules, Rule{
+		ID:    id,
+		Label: table,
+		Table: table,
+		Type:  ruleType,
+	})
+
+	p.prov.Edges = append(p.prov.Edges, Edge{
+		From: head,
+		To:   id,
+	})
+
+	for _, b := range body {
+		p.prov.Edges = append(p.prov.Edges, Edge{
+			From: id,
+			To:   b,
+		})
+	}
+}
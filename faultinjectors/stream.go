@@ -0,0 +1,223 @@
+package faultinjectors
+
+import (
+	"fmt"
+	"io"
+
+	"encoding/json"
+)
+
+// Interfaces.
+
+// ProvSink consumes a provenance graph element by
+// element, e.g., to import it into a graph database
+// without holding the whole graph in memory.
+type ProvSink interface {
+	AddGoal(goal *Goal) error
+	AddRule(rule *Rule) error
+	AddEdge(edge *Edge) error
+}
+
+// ProvStreamer supplies the antecedent or consequent
+// provenance of a run to a ProvSink.
+type ProvStreamer interface {
+	StreamProv(iteration uint, condition string, sink ProvSink) error
+}
+
+// Structs.
+
+// prefixSink makes streamed goals, rules, and edges
+// unique across runs and conditions the same way
+// prefixProv does, before passing them on.
+type prefixSink struct {
+	prefix string
+	clock  bool
+	next   ProvSink
+}
+
+// Functions.
+
+// StreamProvData passes all goals, rules, and edges
+// of provData to sink, in this order.
+func StreamProvData(provData *ProvData, sink ProvSink) error {
+
+	if provData == nil {
+		return nil
+	}
+
+	for j := range provData.Goals {
+
+		err := sink.AddGoal(&provData.Goals[j])
+		if err != nil {
+			return err
+		}
+	}
+
+	for j := range provData.Rules {
+
+		err := sink.AddRule(&provData.Rules[j])
+		if err != nil {
+			return err
+		}
+	}
+
+	for j := range provData.Edges {
+
+		err := sink.AddEdge(&provData.Edges[j])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StreamProv passes the provenance of specified run and
+// condition, held in memory, to sink.
+func (o *Output) StreamProv(iteration uint, condition string, sink ProvSink) error {
+
+	if iteration >= uint(len(o.Runs)) {
+		return fmt.Errorf("Run %d does not exist", iteration)
+	}
+
+	if condition == "pre" {
+		return StreamProvData(o.Runs[iteration].PreProv, sink)
+	}
+
+	return StreamProvData(o.Runs[iteration].PostProv, sink)
+}
+
+// expectDelim reads the next token of dec and
+// fails unless it is delimiter delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	if d, ok := tok.(json.Delim); !ok || (d != delim) {
+		return fmt.Errorf("expected '%v', found %v", delim, tok)
+	}
+
+	return nil
+}
+
+// decodeProvStream decodes a provenance graph object
+// {"goals": [...], "rules": [...], "edges": [...]}
+// from r one element at a time and passes each to sink.
+// Only one element is held in memory at any time.
+func decodeProvStream(r io.Reader, sink ProvSink) error {
+
+	dec := json.NewDecoder(r)
+
+	err := expectDelim(dec, '{')
+	if err != nil {
+		return err
+	}
+
+	for dec.More() {
+
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		key, _ := tok.(string)
+
+		if (key != "goals") && (key != "rules") && (key != "edges") {
+
+			// Skip unknown fields.
+			var skip json.RawMessage
+			err := dec.Decode(&skip)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+
+		// Tolerate null instead of an empty list.
+		if tok == nil {
+			continue
+		}
+
+		if d, ok := tok.(json.Delim); !ok || (d != '[') {
+			return fmt.Errorf("expected list as value of field '%s', found %v", key, tok)
+		}
+
+		for dec.More() {
+
+			switch key {
+
+			case "goals":
+				goal := &Goal{}
+				err = dec.Decode(goal)
+				if err == nil {
+					err = sink.AddGoal(goal)
+				}
+
+			case "rules":
+				rule := &Rule{}
+				err = dec.Decode(rule)
+				if err == nil {
+					err = sink.AddRule(rule)
+				}
+
+			case "edges":
+				edge := &Edge{}
+				err = dec.Decode(edge)
+				if err == nil {
+					err = sink.AddEdge(edge)
+				}
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		err = expectDelim(dec, ']')
+		if err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(dec, '}')
+}
+
+// AddGoal prefixes the goal's ID and marks it as
+// not (yet) achieving the condition.
+func (p *prefixSink) AddGoal(goal *Goal) error {
+
+	if p.clock {
+		fixClockTime(goal)
+	}
+
+	goal.ID = p.prefix + goal.ID
+	goal.CondHolds = false
+
+	return p.next.AddGoal(goal)
+}
+
+// AddRule prefixes the rule's ID.
+func (p *prefixSink) AddRule(rule *Rule) error {
+
+	rule.ID = p.prefix + rule.ID
+
+	return p.next.AddRule(rule)
+}
+
+// AddEdge prefixes the edge's endpoints.
+func (p *prefixSink) AddEdge(edge *Edge) error {
+
+	edge.From = p.prefix + edge.From
+	edge.To = p.prefix + edge.To
+
+	return p.next.AddEdge(edge)
+}
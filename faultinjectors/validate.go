@@ -0,0 +1,363 @@
+package faultinjectors
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"encoding/json"
+)
+
+// Structs.
+
+// provValidator checks a provenance graph element by
+// element, keeping only IDs and edge endpoints in memory.
+// Goal number j is recorded as j+1, rule number j as
+// -(j+1), edge number j as j.
+type provValidator struct {
+	problems  *Problems
+	file      string
+	path      string
+	ids       map[string]int
+	endpoints map[[2]string]int
+	goals     int
+	rules     int
+	edges     int
+	deferred  []deferredEdge
+}
+
+// deferredEdge is an edge preceding one of its
+// endpoints in a streamed provenance graph.
+type deferredEdge struct {
+	index int
+	edge  Edge
+}
+
+// Functions.
+
+// String renders a problem as file, path, and message.
+func (p *Problem) String() string {
+
+	if p.Path == "" {
+		return fmt.Sprintf("%s: %s", p.File, p.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", p.File, p.Path, p.Message)
+}
+
+// Error lists all problems, one per line.
+func (p Problems) Error() string {
+
+	lines := make([]string, 0, (len(p) + 1))
+	lines = append(lines, fmt.Sprintf("Found %d problem(s) in fault injector output:", len(p)))
+	for i := range p {
+		lines = append(lines, fmt.Sprintf("\t%s", p[i]))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// add records a problem at path in file.
+func (p *Problems) add(file string, path string, format string, args ...interface{}) {
+
+	*p = append(*p, &Problem{
+		File:    file,
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns the problems as error, or nil if there are none.
+func (p Problems) err() error {
+
+	if len(p) == 0 {
+		return nil
+	}
+
+	return p
+}
+
+// location renders byte offset into content as line
+// and column, both starting at 1, or as plain offset
+// if content was streamed and is not at hand.
+func location(content []byte, offset int64) string {
+
+	if content == nil {
+		return fmt.Sprintf("byte %d", offset)
+	}
+
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := int(offset) - bytes.LastIndex(before, []byte("\n"))
+
+	return fmt.Sprintf("line %d, column %d", line, col)
+}
+
+// addJSONError records why content of file could not
+// be decoded, located as precisely as possible for
+// JSON syntax and type errors.
+func (p *Problems) addJSONError(file string, content []byte, err error) {
+
+	switch e := err.(type) {
+
+	case *json.SyntaxError:
+		p.add(file, location(content, e.Offset), "invalid JSON: %v", e)
+
+	case *json.UnmarshalTypeError:
+		path := "$"
+		if e.Field != "" {
+			path = fmt.Sprintf("$.%s", e.Field)
+		}
+		p.add(file, fmt.Sprintf("%s (%s)", path, location(content, e.Offset)), "expected %v, found JSON %s", e.Type, e.Value)
+
+	default:
+		p.add(file, "", "%v", err)
+	}
+}
+
+// validateRun checks the fields of the run at position
+// pos, located at path in file, that analyses rely on.
+func (p *Problems) validateRun(file string, path string, pos int, run *Run) {
+
+	if run == nil {
+		p.add(file, path, "run is null")
+		return
+	}
+
+	// Runs are addressed by iteration throughout,
+	// thus iterations have to count up from zero.
+	if run.Iteration != uint(pos) {
+		p.add(file, (path + ".iteration"), "run at position %d has iteration %d, expected iterations to count up from 0", pos, run.Iteration)
+	}
+
+	if (run.Status != "success") && (run.Status != "failure") {
+		p.add(file, (path + ".status"), "status is '%s', expected 'success' or 'failure'", run.Status)
+	}
+
+	nodes := make(map[string]bool)
+
+	if run.FailureSpec == nil {
+		p.add(file, (path + ".failureSpec"), "failure specification is missing")
+	} else {
+
+		spec := run.FailureSpec
+
+		if spec.Nodes == nil {
+			p.add(file, (path + ".failureSpec.nodes"), "list of nodes is missing")
+		} else {
+			for j, node := range *spec.Nodes {
+
+				if nodes[node] {
+					p.add(file, fmt.Sprintf("%s.failureSpec.nodes[%d]", path, j), "duplicate node '%s'", node)
+				}
+				nodes[node] = true
+			}
+		}
+
+		if spec.Crashes != nil {
+			for j, crash := range *spec.Crashes {
+
+				if (spec.Nodes != nil) && !nodes[crash.Node] {
+					p.add(file, fmt.Sprintf("%s.failureSpec.crashes[%d].node", path, j), "unknown node '%s'", crash.Node)
+				}
+
+				if crash.Time > spec.EOT {
+					p.add(file, fmt.Sprintf("%s.failureSpec.crashes[%d].time", path, j), "time %d lies beyond end of time %d", crash.Time, spec.EOT)
+				}
+			}
+		}
+
+		if spec.Omissions != nil {
+			for j, omission := range *spec.Omissions {
+
+				if (spec.Nodes != nil) && !nodes[omission.From] {
+					p.add(file, fmt.Sprintf("%s.failureSpec.omissions[%d].from", path, j), "unknown node '%s'", omission.From)
+				}
+
+				if (spec.Nodes != nil) && !nodes[omission.To] {
+					p.add(file, fmt.Sprintf("%s.failureSpec.omissions[%d].to", path, j), "unknown node '%s'", omission.To)
+				}
+
+				if omission.Time > spec.EOT {
+					p.add(file, fmt.Sprintf("%s.failureSpec.omissions[%d].time", path, j), "time %d lies beyond end of time %d", omission.Time, spec.EOT)
+				}
+			}
+		}
+	}
+
+	if run.Model != nil {
+		for _, table := range []string{"pre", "post"} {
+			for j, row := range run.Model.Tables[table] {
+
+				if len(row) == 0 {
+					p.add(file, fmt.Sprintf("%s.model.tables.%s[%d]", path, table, j), "row is empty, expected time in last column")
+				}
+			}
+		}
+	}
+
+	for j, msg := range run.Messages {
+
+		msgPath := fmt.Sprintf("%s.messages[%d]", path, j)
+
+		if msg == nil {
+			p.add(file, msgPath, "message is null")
+			continue
+		}
+
+		if (len(nodes) > 0) && !nodes[msg.SendNode] {
+			p.add(file, (msgPath + ".from"), "unknown node '%s'", msg.SendNode)
+		}
+
+		if (len(nodes) > 0) && !nodes[msg.RecvNode] {
+			p.add(file, (msgPath + ".to"), "unknown node '%s'", msg.RecvNode)
+		}
+
+		if msg.RecvTime < msg.SendTime {
+			p.add(file, (msgPath + ".receiveTime"), "message received at %d before being sent at %d", msg.RecvTime, msg.SendTime)
+		}
+	}
+}
+
+// validateProv checks that all goal and rule IDs of
+// provData, located at path in file, are present and
+// unique, and that every edge connects a goal and a
+// rule of the graph.
+func (p *Problems) validateProv(file string, path string, provData *ProvData) {
+
+	if provData == nil {
+		p.add(file, path, "provenance graph is missing")
+		return
+	}
+
+	v := newProvValidator(p, file, path)
+
+	// The validator records problems instead of failing.
+	_ = StreamProvData(provData, v)
+
+	v.finish()
+}
+
+// newProvValidator prepares checking a streamed
+// provenance graph located at path in file.
+func newProvValidator(problems *Problems, file string, path string) *provValidator {
+
+	return &provValidator{
+		problems:  problems,
+		file:      file,
+		path:      path,
+		ids:       make(map[string]int),
+		endpoints: make(map[[2]string]int),
+	}
+}
+
+// idPath returns the path of the goal or rule
+// ID was first used for.
+func (v *provValidator) idPath(id string) string {
+
+	pos := v.ids[id]
+	if pos > 0 {
+		return fmt.Sprintf("%s.goals[%d]", v.path, (pos - 1))
+	}
+
+	return fmt.Sprintf("%s.rules[%d]", v.path, (-pos - 1))
+}
+
+// AddGoal checks the ID of the next goal.
+func (v *provValidator) AddGoal(goal *Goal) error {
+
+	goalPath := fmt.Sprintf("%s.goals[%d]", v.path, v.goals)
+	v.goals++
+
+	if goal.ID == "" {
+		v.problems.add(v.file, (goalPath + ".id"), "goal lacks an ID")
+	} else if _, dup := v.ids[goal.ID]; dup {
+		v.problems.add(v.file, (goalPath + ".id"), "duplicate ID '%s', first used at %s", goal.ID, v.idPath(goal.ID))
+	} else {
+		v.ids[goal.ID] = v.goals
+	}
+
+	return nil
+}
+
+// AddRule checks the ID of the next rule.
+func (v *provValidator) AddRule(rule *Rule) error {
+
+	rulePath := fmt.Sprintf("%s.rules[%d]", v.path, v.rules)
+	v.rules++
+
+	if rule.ID == "" {
+		v.problems.add(v.file, (rulePath + ".id"), "rule lacks an ID")
+	} else if _, dup := v.ids[rule.ID]; dup {
+		v.problems.add(v.file, (rulePath + ".id"), "duplicate ID '%s', first used at %s", rule.ID, v.idPath(rule.ID))
+	} else {
+		v.ids[rule.ID] = -v.rules
+	}
+
+	return nil
+}
+
+// AddEdge checks that the next edge connects its endpoints
+// for the first time. It checks the endpoints themselves if
+// both are known already, and defers that otherwise.
+func (v *provValidator) AddEdge(edge *Edge) error {
+
+	endpoints := [2]string{edge.From, edge.To}
+	if first, dup := v.endpoints[endpoints]; dup {
+		v.problems.add(v.file, fmt.Sprintf("%s.edges[%d]", v.path, v.edges), "duplicate edge %s -> %s, first at %s.edges[%d]", edge.From, edge.To, v.path, first)
+	} else {
+		v.endpoints[endpoints] = v.edges
+	}
+
+	_, fromFound := v.ids[edge.From]
+	_, toFound := v.ids[edge.To]
+
+	if fromFound && toFound {
+		v.checkEdge(v.edges, edge)
+	} else {
+		v.deferred = append(v.deferred, deferredEdge{
+			index: v.edges,
+			edge:  *edge,
+		})
+	}
+
+	v.edges++
+
+	return nil
+}
+
+// checkEdge checks edge number j.
+func (v *provValidator) checkEdge(j int, edge *Edge) {
+
+	edgePath := fmt.Sprintf("%s.edges[%d]", v.path, j)
+
+	from, fromFound := v.ids[edge.From]
+	to, toFound := v.ids[edge.To]
+
+	if !fromFound {
+		v.problems.add(v.file, (edgePath + ".from"), "dangling edge, no goal or rule with ID '%s'", edge.From)
+	}
+
+	if !toFound {
+		v.problems.add(v.file, (edgePath + ".to"), "dangling edge, no goal or rule with ID '%s'", edge.To)
+	}
+
+	if fromFound && toFound && ((from > 0) == (to > 0)) {
+		v.problems.add(v.file, edgePath, "edge %s -> %s does not connect a goal and a rule", edge.From, edge.To)
+	}
+}
+
+// finish checks all edges that preceded
+// one of their endpoints in the stream.
+func (v *provValidator) finish() {
+
+	for i := range v.deferred {
+		v.checkEdge(v.deferred[i].index, &v.deferred[i].edge)
+	}
+
+	v.deferred = nil
+}
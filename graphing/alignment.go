@@ -0,0 +1,334 @@
+package graphing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Constants.
+
+const (
+	// alignMatched marks a node of the successful run
+	// that occurs identically in the failed run.
+	alignMatched = "matched"
+
+	// alignShifted marks a node of the successful run
+	// that occurs in the failed run at a different time.
+	alignShifted = "shifted"
+
+	// alignMissing marks a node of the successful run
+	// that has no counterpart in the failed run.
+	alignMissing = "missing"
+)
+
+// Structs.
+
+// nodeAlignment is the category of one node of the
+// successful run and, for shifted ones, by how many
+// time steps its counterpart in the failed run is off.
+type nodeAlignment struct {
+	category    string
+	shift       int
+	counterpart string
+}
+
+// provAlignment aligns the provenance of a successful
+// run with the one of a failed run. Nodes are keyed by
+// ID in the successful run's graph, aligned marks the
+// goals of the failed run that have a counterpart.
+type provAlignment struct {
+	nodes   map[string]*nodeAlignment
+	aligned map[string]bool
+}
+
+// Functions.
+
+// splitArgs splits the arguments of a goal label at
+// top-level commas, i.e., not inside parentheses,
+// brackets, braces, or quotes.
+func splitArgs(args string) []string {
+
+	split := make([]string, 0, 4)
+	depth := 0
+	quoted := false
+	start := 0
+
+	for i, c := range args {
+
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+		case (c == '(') || (c == '[') || (c == '{'):
+			depth++
+		case (c == ')') || (c == ']') || (c == '}'):
+			depth--
+		case (c == ',') && (depth == 0):
+			split = append(split, strings.TrimSpace(args[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(split, strings.TrimSpace(args[start:]))
+}
+
+// timePattern abstracts the time away from a goal: it
+// keeps the goal's table and arguments but expresses the
+// time column, its last argument, relative to the time of
+// the goal. All other arguments stay literal. Goals that
+// differ only in a time shift share a pattern.
+func timePattern(goal *fi.Goal) string {
+
+	t, err := strconv.Atoi(goal.Time)
+	if err != nil {
+		return goal.Label
+	}
+
+	open := strings.Index(goal.Label, "(")
+	if (open == -1) || !strings.HasSuffix(goal.Label, ")") {
+		return fmt.Sprintf("%s@t", goal.Label)
+	}
+
+	args := splitArgs(goal.Label[(open + 1):(len(goal.Label) - 1)])
+
+	last := len(args) - 1
+	if n, err := strconv.Atoi(args[last]); err == nil {
+		args[last] = fmt.Sprintf("t%+d", n-t)
+	}
+
+	return fmt.Sprintf("%s(%s)", goal.Table, strings.Join(args, ", "))
+}
+
+// goalShift returns how many time steps goal other
+// happens after goal, zero if times are not numeric.
+func goalShift(goal *fi.Goal, other *fi.Goal) int {
+
+	t, err := strconv.Atoi(goal.Time)
+	if err != nil {
+		return 0
+	}
+
+	o, err := strconv.Atoi(other.Time)
+	if err != nil {
+		return 0
+	}
+
+	return o - t
+}
+
+// alignProv aligns the provenance good of a successful
+// run with the provenance bad of a failed run. A goal is
+// matched if a goal with the same label exists in bad,
+// shifted if one with the same time pattern exists (the
+// one closest in time is its counterpart), and missing
+// otherwise. A rule takes the category of its head goal
+// if the counterpart of that goal is derived by a rule of
+// the same table and type, and is missing otherwise.
+func alignProv(good *provGraph, bad *provGraph) *provAlignment {
+
+	a := &provAlignment{
+		nodes:   make(map[string]*nodeAlignment),
+		aligned: make(map[string]bool),
+	}
+
+	byLabel := make(map[string][]string)
+	byPattern := make(map[string][]string)
+	for _, id := range bad.order {
+
+		if bad.isGoal(id) {
+			byLabel[bad.goals[id].Label] = append(byLabel[bad.goals[id].Label], id)
+			byPattern[timePattern(bad.goals[id])] = append(byPattern[timePattern(bad.goals[id])], id)
+		}
+	}
+
+	for _, id := range good.order {
+
+		if !good.isGoal(id) {
+			continue
+		}
+		goal := good.goals[id]
+
+		if same := byLabel[goal.Label]; len(same) > 0 {
+
+			a.nodes[id] = &nodeAlignment{
+				category:    alignMatched,
+				counterpart: same[0],
+			}
+			a.aligned[same[0]] = true
+
+			continue
+		}
+
+		closest := ""
+		for _, badID := range byPattern[timePattern(goal)] {
+
+			shift := goalShift(goal, bad.goals[badID])
+			if (closest == "") || (abs(shift) < abs(goalShift(goal, bad.goals[closest]))) {
+				closest = badID
+			}
+		}
+
+		if closest == "" {
+			a.nodes[id] = &nodeAlignment{category: alignMissing}
+			continue
+		}
+
+		a.nodes[id] = &nodeAlignment{
+			category:    alignShifted,
+			shift:       goalShift(goal, bad.goals[closest]),
+			counterpart: closest,
+		}
+		a.aligned[closest] = true
+	}
+
+	for _, id := range good.order {
+
+		if !good.isRule(id) {
+			continue
+		}
+		rule := good.rules[id]
+
+		a.nodes[id] = &nodeAlignment{category: alignMissing}
+
+		for _, headID := range good.preds[id] {
+
+			head := a.nodes[headID]
+			if (head == nil) || (head.category == alignMissing) {
+				continue
+			}
+
+			for _, badID := range bad.succs[head.counterpart] {
+
+				badRule := bad.rules[badID]
+				if (badRule != nil) && (badRule.Table == rule.Table) && (badRule.Type == rule.Type) {
+
+					a.nodes[id] = &nodeAlignment{
+						category:    head.category,
+						shift:       head.shift,
+						counterpart: badID,
+					}
+				}
+			}
+		}
+	}
+
+	return a
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
+
+// missing reports whether goal id of the
+// successful run has no counterpart.
+func (a *provAlignment) missing(id string) bool {
+	return (a.nodes[id] != nil) && (a.nodes[id].category == alignMissing)
+}
+
+// renamed returns the alignment with node IDs of the
+// successful run's graph carrying prefix newPrefix
+// instead of oldPrefix. IDs not starting with oldPrefix
+// are left out.
+func (a *provAlignment) renamed(oldPrefix string, newPrefix string) map[string]*nodeAlignment {
+
+	nodes := make(map[string]*nodeAlignment, len(a.nodes))
+	for id := range a.nodes {
+
+		if !strings.HasPrefix(id, oldPrefix) {
+			continue
+		}
+
+		nodes[newPrefix+strings.TrimPrefix(id, oldPrefix)] = a.nodes[id]
+	}
+
+	return nodes
+}
+
+// summary condenses the alignment for export, listing
+// all shifted goals along with their counterparts.
+func (a *provAlignment) summary(good *provGraph, bad *provGraph) *fi.ProvAlignment {
+
+	s := &fi.ProvAlignment{
+		ShiftedGoals: make([]*fi.ShiftedGoal, 0, 2),
+	}
+
+	for _, id := range good.order {
+
+		n := a.nodes[id]
+		if n == nil {
+			continue
+		}
+
+		switch n.category {
+		case alignMatched:
+			s.Matched++
+		case alignShifted:
+			s.Shifted++
+		case alignMissing:
+			s.Missing++
+		}
+
+		if (n.category == alignShifted) && good.isGoal(id) {
+
+			s.ShiftedGoals = append(s.ShiftedGoals, &fi.ShiftedGoal{
+				Label:       good.goals[id].Label,
+				FailedLabel: bad.goals[n.counterpart].Label,
+				Shift:       n.shift,
+			})
+		}
+	}
+
+	return s
+}
+
+// alignRuns aligns the provenance of each failed run
+// with the one of its successful run in successRuns,
+// pulling provenance graphs via prov.
+func alignRuns(failedRuns []uint, successRuns []uint, prov func(run uint) (*provGraph, error)) ([]*fi.ProvAlignment, error) {
+
+	fmt.Printf("Aligning provenance of failed and successful runs... ")
+
+	alignments := make([]*fi.ProvAlignment, len(failedRuns))
+
+	for i := range failedRuns {
+
+		good, err := prov(successRuns[i])
+		if err != nil {
+			return nil, err
+		}
+
+		bad, err := prov(failedRuns[i])
+		if err != nil {
+			return nil, err
+		}
+
+		alignments[i] = alignProv(good, bad).summary(good, bad)
+	}
+
+	fmt.Printf("done\n\n")
+
+	return alignments, nil
+}
+
+// AlignProv
+func (n *Neo4J) AlignProv(failedRuns []uint, successRuns []uint) ([]*fi.ProvAlignment, error) {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return alignRuns(failedRuns, successRuns, func(run uint) (*provGraph, error) {
+		return n.pullProvGraph(conn, KindRaw, run, "post")
+	})
+}
@@ -0,0 +1,198 @@
+package graphing
+
+import (
+	"testing"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Functions.
+
+// testGoal builds a goal of table at time from its arguments.
+func testGoal(id string, table string, time string, args string) fi.Goal {
+
+	return fi.Goal{
+		ID:    id,
+		Label: table + "(" + args + ")",
+		Table: table,
+		Time:  time,
+	}
+}
+
+func TestTimePattern(t *testing.T) {
+
+	tests := []struct {
+		goal    fi.Goal
+		pattern string
+	}{
+		{testGoal("g", "ack", "3", "b, a, 7, 3"), "ack(b, a, 7, t+0)"},
+		{testGoal("g", "ack", "4", "b, a, 7, 4"), "ack(b, a, 7, t+0)"},
+		{testGoal("g", "log", "3", "b, 4, 3"), "log(b, 4, t+0)"},
+		{testGoal("g", "log", "4", "b, 5, 4"), "log(b, 5, t+0)"},
+		{testGoal("g", "log", "4", "b, 4, 4"), "log(b, 4, t+0)"},
+		{testGoal("g", "timer", "2", "a, 5"), "timer(a, t+3)"},
+		{testGoal("g", "msg", "2", "a, \"x, 1\", [1, 2]"), "msg(a, \"x, 1\", [1, 2])"},
+		{testGoal("g", "crash", "NOTIME", "a, 2"), "crash(a, 2)"},
+		{fi.Goal{ID: "g", Label: "pre", Table: "pre", Time: "5"}, "pre@t"},
+	}
+
+	for _, tt := range tests {
+
+		if pattern := timePattern(&tt.goal); pattern != tt.pattern {
+			t.Errorf("pattern of %s at %s is '%s', expected '%s'", tt.goal.Label, tt.goal.Time, pattern, tt.pattern)
+		}
+	}
+}
+
+func TestAlignProv(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		good     fi.Goal
+		bad      fi.Goal
+		category string
+		shift    int
+	}{
+		{
+			name:     "identical",
+			good:     testGoal("good", "ack", "3", "b, a, 7, 3"),
+			bad:      testGoal("bad", "ack", "3", "b, a, 7, 3"),
+			category: alignMatched,
+		},
+		{
+			name:     "one tick later",
+			good:     testGoal("good", "ack", "3", "b, a, 7, 3"),
+			bad:      testGoal("bad", "ack", "4", "b, a, 7, 4"),
+			category: alignShifted,
+			shift:    1,
+		},
+		{
+			name:     "one tick earlier",
+			good:     testGoal("good", "ack", "4", "b, a, 7, 4"),
+			bad:      testGoal("bad", "ack", "3", "b, a, 7, 3"),
+			category: alignShifted,
+			shift:    -1,
+		},
+		{
+			name:     "different value",
+			good:     testGoal("good", "log", "3", "b, 4, 3"),
+			bad:      testGoal("bad", "log", "4", "b, 5, 4"),
+			category: alignMissing,
+		},
+		{
+			name:     "different table",
+			good:     testGoal("good", "ack", "3", "b, a, 7, 3"),
+			bad:      testGoal("bad", "log", "3", "b, a, 7, 3"),
+			category: alignMissing,
+		},
+	}
+
+	for _, tt := range tests {
+
+		good := newProvGraph()
+		good.addGoal(tt.good)
+		good.addRule(fi.Rule{ID: "goodRule", Label: tt.good.Table, Table: tt.good.Table, Type: "async"})
+		good.addEdge("good", "goodRule")
+
+		bad := newProvGraph()
+		bad.addGoal(tt.bad)
+		bad.addRule(fi.Rule{ID: "badRule", Label: tt.bad.Table, Table: tt.bad.Table, Type: "async"})
+		bad.addEdge("bad", "badRule")
+
+		a := alignProv(good, bad)
+
+		for _, id := range []string{"good", "goodRule"} {
+
+			n := a.nodes[id]
+			if n == nil {
+				t.Fatalf("%s: %s not aligned", tt.name, id)
+			}
+
+			if (n.category != tt.category) || (n.shift != tt.shift) {
+				t.Errorf("%s: %s is %s by %d, expected %s by %d", tt.name, id, n.category, n.shift, tt.category, tt.shift)
+			}
+		}
+
+		if a.aligned["bad"] != (tt.category != alignMissing) {
+			t.Errorf("%s: failed run's goal aligned is %t", tt.name, a.aligned["bad"])
+		}
+	}
+}
+
+func TestAlignProvCandidates(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		bad         []fi.Goal
+		category    string
+		counterpart string
+	}{
+		{
+			name: "two matching goals",
+			bad: []fi.Goal{
+				testGoal("bad1", "ack", "3", "b, a, 7, 3"),
+				testGoal("bad2", "ack", "3", "b, a, 7, 3"),
+			},
+			category:    alignMatched,
+			counterpart: "bad1",
+		},
+		{
+			name: "two shifted goals",
+			bad: []fi.Goal{
+				testGoal("bad1", "ack", "6", "b, a, 7, 6"),
+				testGoal("bad2", "ack", "4", "b, a, 7, 4"),
+			},
+			category:    alignShifted,
+			counterpart: "bad2",
+		},
+	}
+
+	for _, tt := range tests {
+
+		good := newProvGraph()
+		good.addGoal(testGoal("good", "ack", "3", "b, a, 7, 3"))
+
+		bad := newProvGraph()
+		for _, goal := range tt.bad {
+			bad.addGoal(goal)
+		}
+
+		a := alignProv(good, bad)
+
+		n := a.nodes["good"]
+		if (n.category != tt.category) || (n.counterpart != tt.counterpart) {
+			t.Errorf("%s: good is %s to %s, expected %s to %s", tt.name, n.category, n.counterpart, tt.category, tt.counterpart)
+		}
+
+		for _, goal := range tt.bad {
+
+			if a.aligned[goal.ID] != (goal.ID == tt.counterpart) {
+				t.Errorf("%s: failed run's goal %s aligned is %t", tt.name, goal.ID, a.aligned[goal.ID])
+			}
+		}
+	}
+}
+
+func TestAlignmentRenamed(t *testing.T) {
+
+	a := &provAlignment{
+		nodes: map[string]*nodeAlignment{
+			"run_1_post_goal1":       {category: alignMatched},
+			"run_1_post_rule1":       {category: alignMissing},
+			"other_run_1_post_goal2": {category: alignMatched},
+		},
+	}
+
+	nodes := a.renamed("run_1_post", "diff_1_post")
+
+	for _, id := range []string{"diff_1_post_goal1", "diff_1_post_rule1"} {
+
+		if nodes[id] == nil {
+			t.Errorf("%s missing after renaming", id)
+		}
+	}
+
+	if len(nodes) != 2 {
+		t.Errorf("renaming kept %d nodes, expected 2", len(nodes))
+	}
+}
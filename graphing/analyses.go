@@ -0,0 +1,257 @@
+package graphing
+
+import (
+	"fmt"
+	"time"
+)
+
+// Structs.
+
+// Analysis describes one debugging analysis stored in
+// a graph database. All nodes and relationships created
+// while analyzing one fault injector output carry the
+// analysis' ID, so that several analyses can share a
+// database without interfering.
+type Analysis struct {
+	ID      string `json:"id"`
+	Created string `json:"created"`
+	Runs    int64  `json:"runs"`
+}
+
+// Functions.
+
+// nodeUID returns the database-wide unique identifier
+// of the node with ID id in the specified analysis.
+func nodeUID(analysis string, id string) string {
+	return fmt.Sprintf("%s/%s", analysis, id)
+}
+
+// createSchema ensures the constraints and indexes all
+// analyses rely on exist.
+func (n *Neo4J) createSchema() error {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stmts := []string{
+		"CREATE CONSTRAINT ON (a:Analysis) ASSERT a.id IS UNIQUE;",
+		"CREATE CONSTRAINT ON (goal:Goal) ASSERT goal.uid IS UNIQUE;",
+		"CREATE CONSTRAINT ON (rule:Rule) ASSERT rule.uid IS UNIQUE;",
+		"CREATE INDEX ON :Goal(analysis);",
+		"CREATE INDEX ON :Goal(run);",
+		"CREATE INDEX ON :Rule(analysis);",
+		"CREATE INDEX ON :Rule(run);",
+	}
+
+	for i := range stmts {
+
+		_, err := conn.ExecNeo(stmts[i], nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// registerAnalysis records the current analysis
+// in the graph database.
+func (n *Neo4J) registerAnalysis() error {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecNeo(`
+		MERGE (a:Analysis {id: {analysis}})
+		SET a.created = {created}, a.runs = {runs};
+	`, map[string]interface{}{
+		"analysis": n.Analysis,
+		"created":  time.Now().UTC().Format(time.RFC3339),
+		"runs":     len(n.Runs),
+	})
+
+	return err
+}
+
+// ListAnalyses returns all analyses stored in
+// the graph database, oldest first.
+func (n *Neo4J) ListAnalyses() ([]*Analysis, error) {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	stmtList, err := conn.PrepareNeo(`
+		MATCH (a:Analysis)
+		RETURN a.id, a.created, a.runs
+		ORDER BY a.created, a.id;
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	analysesRaw, err := stmtList.QueryNeo(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	analysesAll, _, err := analysesRaw.All()
+	if err != nil {
+		return nil, err
+	}
+
+	err = analysesRaw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = stmtList.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	analyses := make([]*Analysis, 0, len(analysesAll))
+	for i := range analysesAll {
+
+		a := &Analysis{
+			ID: analysesAll[i][0].(string),
+		}
+
+		if created, ok := analysesAll[i][1].(string); ok {
+			a.Created = created
+		}
+
+		if runs, ok := analysesAll[i][2].(int64); ok {
+			a.Runs = runs
+		}
+
+		analyses = append(analyses, a)
+	}
+
+	return analyses, nil
+}
+
+// DeleteAnalysis removes all nodes and relationships
+// belonging to the specified analysis.
+func (n *Neo4J) DeleteAnalysis(analysis string) error {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stmts := []string{
+		"MATCH (goal:Goal {analysis: {analysis}}) DETACH DELETE goal;",
+		"MATCH (rule:Rule {analysis: {analysis}}) DETACH DELETE rule;",
+		"MATCH (a:Analysis {id: {analysis}}) DELETE a;",
+	}
+
+	for i := range stmts {
+
+		_, err := conn.ExecNeo(stmts[i], map[string]interface{}{
+			"analysis": analysis,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RestoreCachedProv checks whether the raw and simplified
+// provenance stored for the current analysis was imported
+// from inputs with the supplied fingerprint. If so, it
+// removes all graphs derived later on and reports that
+// import and simplification can be skipped. It refuses to
+// let an analysis of different inputs be replaced.
+func (n *Neo4J) RestoreCachedProv(fingerprint string) (bool, error) {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	fingerprintRows, err := conn.QueryNeo(`
+		MATCH (a:Analysis {id: {analysis}})
+		RETURN a.fingerprint;
+	`, map[string]interface{}{
+		"analysis": n.Analysis,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	fingerprintAll, _, err := fingerprintRows.All()
+	if err != nil {
+		return false, err
+	}
+
+	err = fingerprintRows.Close()
+	if err != nil {
+		return false, err
+	}
+
+	if len(fingerprintAll) == 0 {
+		return false, nil
+	}
+
+	stored, ok := fingerprintAll[0][0].(string)
+	if !ok {
+		return false, nil
+	}
+
+	if stored != fingerprint {
+		return false, fmt.Errorf("Analysis '%s' holds provenance of different fault injector output, pass -force-reimport to replace it", n.Analysis)
+	}
+
+	stmts := []string{
+		"MATCH (goal:Goal {analysis: {analysis}}) WHERE NOT goal.kind IN {cached} DETACH DELETE goal;",
+		"MATCH (rule:Rule {analysis: {analysis}}) WHERE NOT rule.kind IN {cached} DETACH DELETE rule;",
+	}
+
+	for i := range stmts {
+
+		_, err := conn.ExecNeo(stmts[i], map[string]interface{}{
+			"analysis": n.Analysis,
+			"cached":   []interface{}{string(KindRaw), string(KindClean)},
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// CacheProv records that the raw and simplified provenance
+// of the current analysis stem from inputs with the
+// supplied fingerprint.
+func (n *Neo4J) CacheProv(fingerprint string) error {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecNeo(`
+		MATCH (a:Analysis {id: {analysis}})
+		SET a.fingerprint = {fingerprint};
+	`, map[string]interface{}{
+		"analysis":    n.Analysis,
+		"fingerprint": fingerprint,
+	})
+
+	return err
+}
@@ -0,0 +1,85 @@
+package graphing
+
+import (
+	"fmt"
+	"strings"
+
+	"os/exec"
+)
+
+// Interfaces.
+
+// ContainerManager starts and stops the containers
+// a graph database server runs in. Neo4J uses one
+// only if configured to, otherwise it expects the
+// server to be running already.
+type ContainerManager interface {
+	Start() error
+	Stop() error
+}
+
+// Structs.
+
+// DockerCompose manages the graph database container
+// defined in a docker-compose file.
+type DockerCompose struct {
+	ComposeFile string
+	Sudo        bool
+}
+
+// Functions.
+
+// run executes docker-compose with the supplied
+// arguments and checks that it reported success.
+func (d *DockerCompose) run(args ...string) error {
+
+	args = append([]string{"docker-compose", "-f", d.ComposeFile}, args...)
+
+	var cmd *exec.Cmd
+	if d.Sudo {
+		cmd = exec.Command("sudo", args...)
+	} else {
+		cmd = exec.Command(args[0], args[1:]...)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker-compose %s failed: %v: %s", args[3], err, out)
+	}
+
+	if !strings.Contains(string(out), "done") {
+		return fmt.Errorf("Wrong return value from docker-compose %s command: %s", args[3], out)
+	}
+
+	return nil
+}
+
+// Start brings up the containers.
+func (d *DockerCompose) Start() error {
+
+	fmt.Printf("Starting docker containers... ")
+
+	err := d.run("up", "-d")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("done\n")
+
+	return nil
+}
+
+// Stop shuts down the containers.
+func (d *DockerCompose) Stop() error {
+
+	fmt.Printf("Shutting down docker containers... ")
+
+	err := d.run("down")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("done\n")
+
+	return nil
+}
@@ -130,7 +130,12 @@ func createDOT(edges []graph.Path, graphType string) (*gographviz.Graph, error)
 }
 
 // createDiffDot
-func createDiffDot(diffRunID uint, diffEdges []graph.Path, failedRunID uint, failedEdges []graph.Path, successRunID uint, successPostProv *gographviz.Graph, missing []*fi.Missing) (*gographviz.Graph, *gographviz.Graph, error) {
+func createDiffDot(diffEdges []graph.Path, failedRunID uint, failedEdges []graph.Path, successRunID uint, successPostProv *gographviz.Graph, missing []*fi.Missing, alignment map[string]*nodeAlignment) (*gographviz.Graph, *gographviz.Graph, error) {
+
+	// Node IDs of the successful run's graph are mapped
+	// onto the ones of the differential provenance graph.
+	successPrefix := idPrefix(KindRaw, successRunID, "post")
+	diffPrefix := idPrefix(KindDiff, failedRunID, "post")
 
 	// Create map for lookup of missing events.
 	missingMap := make(map[string]bool)
@@ -184,8 +189,8 @@ func createDiffDot(diffRunID uint, diffEdges []graph.Path, failedRunID uint, fai
 
 	for _, edge := range successPostProv.Edges.Edges {
 
-		diffSrc := strings.Replace(edge.Src, fmt.Sprintf("run_%d", successRunID), fmt.Sprintf("run_%d", diffRunID), -1)
-		diffDst := strings.Replace(edge.Dst, fmt.Sprintf("run_%d", successRunID), fmt.Sprintf("run_%d", diffRunID), -1)
+		diffSrc := strings.Replace(edge.Src, successPrefix, diffPrefix, 1)
+		diffDst := strings.Replace(edge.Dst, successPrefix, diffPrefix, 1)
 
 		// Copy attribute map.
 		attrMap := make(map[string]string)
@@ -210,7 +215,7 @@ func createDiffDot(diffRunID uint, diffEdges []graph.Path, failedRunID uint, fai
 
 	for _, node := range successPostProv.Nodes.Nodes {
 
-		diffName := strings.Replace(node.Name, fmt.Sprintf("run_%d", successRunID), fmt.Sprintf("run_%d", diffRunID), -1)
+		diffName := strings.Replace(node.Name, successPrefix, diffPrefix, 1)
 
 		// Copy attribute map.
 		attrMap := make(map[string]string)
@@ -233,6 +238,30 @@ func createDiffDot(diffRunID uint, diffEdges []graph.Path, failedRunID uint, fai
 		}
 	}
 
+	// Show nodes of the successful run that the failed run
+	// derived as well, faded if at the same time, dashed
+	// and annotated with the shift if at a different time.
+	for name, node := range diffDotGraph.Nodes.Lookup {
+
+		a, found := alignment[name]
+		if !found {
+			continue
+		}
+
+		switch a.category {
+		case alignMatched:
+			node.Attrs["style"] = "\"filled, solid\""
+			node.Attrs["color"] = "\"gray60\""
+			node.Attrs["fontcolor"] = "\"gray60\""
+		case alignShifted:
+			node.Attrs["style"] = "\"filled, dashed, bold\""
+			node.Attrs["color"] = "\"goldenrod\""
+			node.Attrs["xlabel"] = fmt.Sprintf("\"%+d\"", a.shift)
+		case alignMissing:
+			node.Attrs["style"] = "\"filled, solid\""
+		}
+	}
+
 	for i := range diffEdges {
 
 		from := diffEdges[i].Nodes[0].Properties["id"].(string)
@@ -264,6 +293,28 @@ func createDiffDot(diffRunID uint, diffEdges []graph.Path, failedRunID uint, fai
 		}
 	}
 
+	// Make edges visible again between visible nodes,
+	// faded if they touch nodes of the failed run.
+	for i := range diffDotGraph.Edges.Edges {
+
+		edge := diffDotGraph.Edges.Edges[i]
+		if edge.Attrs["style"] != "\"invis\"" {
+			continue
+		}
+
+		src := diffDotGraph.Nodes.Lookup[edge.Src]
+		dst := diffDotGraph.Nodes.Lookup[edge.Dst]
+		if (src.Attrs["style"] == "\"invis\"") || (dst.Attrs["style"] == "\"invis\"") {
+			continue
+		}
+
+		edge.Attrs["style"] = "\"filled, solid\""
+		if (alignment[edge.Src] == nil) || (alignment[edge.Src].category != alignMissing) ||
+			(alignment[edge.Dst] == nil) || (alignment[edge.Dst].category != alignMissing) {
+			edge.Attrs["color"] = "\"gray60\""
+		}
+	}
+
 	for i := range failedEdges {
 
 		from := fmt.Sprintf("\"%s\"", failedEdges[i].Nodes[0].Properties["label"].(string))
@@ -289,3 +340,116 @@ func createDiffDot(diffRunID uint, diffEdges []graph.Path, failedRunID uint, fai
 
 	return diffDotGraph, failedDotGraph, err
 }
+
+// createExcessDot lays the derivations only failed run
+// failedRunID made on top of its consequent provenance,
+// hiding everything the successful run derived as well.
+func createExcessDot(excessEdges []graph.Path, failedRunID uint, failedPostProv *gographviz.Graph, excess []*fi.Missing) (*gographviz.Graph, error) {
+
+	// Node IDs of the failed run's graph are mapped onto
+	// the ones of the reverse differential provenance graph.
+	failedPrefix := idPrefix(KindRaw, failedRunID, "post")
+	excessPrefix := idPrefix(KindExcess, failedRunID, "post")
+
+	// Create map for lookup of excess events.
+	excessMap := make(map[string]bool)
+	for e := range excess {
+
+		excessMap[excess[e].Rule.ID] = true
+		for g := range excess[e].Goals {
+			excessMap[excess[e].Goals[g].ID] = true
+		}
+	}
+
+	excessDotGraph := gographviz.NewGraph()
+
+	// Name the DOT graph.
+	err := excessDotGraph.SetName("dataflow")
+	if err != nil {
+		return nil, err
+	}
+
+	// It is a directed graph.
+	err = excessDotGraph.SetDir(true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the background is transparent.
+	err = excessDotGraph.AddNode("dataflow", "graph", map[string]string{
+		"bgcolor": "\"transparent\"",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, edge := range failedPostProv.Edges.Edges {
+
+		excessSrc := strings.Replace(edge.Src, failedPrefix, excessPrefix, 1)
+		excessDst := strings.Replace(edge.Dst, failedPrefix, excessPrefix, 1)
+
+		// Copy attribute map.
+		attrMap := make(map[string]string)
+		for j := range edge.Attrs {
+			attrMap[string(j)] = edge.Attrs[j]
+		}
+
+		// Overwrite style attribute to hide edge.
+		attrMap["style"] = "\"invis\""
+
+		err := excessDotGraph.AddEdge(excessSrc, excessDst, edge.Dir, attrMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, node := range failedPostProv.Nodes.Nodes {
+
+		excessName := strings.Replace(node.Name, failedPrefix, excessPrefix, 1)
+
+		// Copy attribute map.
+		attrMap := make(map[string]string)
+		for j := range node.Attrs {
+			attrMap[string(j)] = node.Attrs[j]
+		}
+
+		// Overwrite style attribute to hide node.
+		attrMap["style"] = "\"invis\""
+
+		err := excessDotGraph.AddNode("dataflow", excessName, attrMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range excessEdges {
+
+		from := excessEdges[i].Nodes[0].Properties["id"].(string)
+		to := excessEdges[i].Nodes[1].Properties["id"].(string)
+
+		// Make nodes and edges visible again that
+		// are part of the selected subgraph.
+		for _, id := range []string{from, to} {
+
+			node, found := excessDotGraph.Nodes.Lookup[id]
+			if !found {
+				continue
+			}
+
+			node.Attrs["style"] = "\"filled, solid\""
+
+			// Mark the frontier of the failed
+			// run's deviation specifically.
+			if excessMap[id] {
+				node.Attrs["style"] = "\"filled, dashed, bold\""
+				node.Attrs["color"] = "\"darkorange\""
+			}
+		}
+
+		for j := range excessDotGraph.Edges.SrcToDsts[from][to] {
+			excessDotGraph.Edges.SrcToDsts[from][to][j].Attrs["style"] = "\"filled, solid\""
+		}
+	}
+
+	return excessDotGraph, nil
+}
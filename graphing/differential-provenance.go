@@ -3,9 +3,6 @@ package graphing
 import (
 	"fmt"
 	"io"
-	"strings"
-
-	"os/exec"
 
 	"github.com/awalterschulze/gographviz"
 	graph "github.com/johnnadratowski/golang-neo4j-bolt-driver/structures/graph"
@@ -14,102 +11,145 @@ import (
 
 // Functions.
 
+// failedOnly returns the reverse differential provenance
+// (bad - good) of failed run failedRun: all paths of its
+// provenance whose start and end goals have no counterpart
+// in the successful run per alignment a, with node IDs in
+// the KindExcess namespace.
+func failedOnly(a *provAlignment, failedProv *provGraph, failedRun uint) *provGraph {
+
+	return failedProv.between(func(goal *fi.Goal) bool {
+		return !a.aligned[goal.ID]
+	}).renamed(idPrefix(KindRaw, failedRun, "post"), idPrefix(KindExcess, failedRun, "post"))
+}
+
+// diffTitle names the differences computed
+// in naive differential provenance.
+func diffTitle(symmetric bool) string {
+
+	if symmetric {
+		return "good - bad, bad - good"
+	}
+
+	return "good - bad"
+}
+
 // CreateNaiveDiffProv
-func (n *Neo4J) CreateNaiveDiffProv(symmetric bool, failedRuns []uint, successPostProv *gographviz.Graph) ([]*gographviz.Graph, []*gographviz.Graph, [][]*fi.Missing, error) {
+func (n *Neo4J) CreateNaiveDiffProv(symmetric bool, failedRuns []uint, successRuns []uint, postProvDots []*gographviz.Graph) ([]*gographviz.Graph, []*gographviz.Graph, [][]*fi.Missing, []*gographviz.Graph, [][]*fi.Missing, error) {
 
-	fmt.Printf("Creating differential provenance (good - bad), naive way... ")
+	fmt.Printf("Creating differential provenance (%s), naive way... ", diffTitle(symmetric))
 
-	exportQuery := `CALL apoc.export.cypher.query("
-		MATCH (failed:Goal {run: ###RUN###, condition: 'post'})
-		WITH collect(failed.label) AS failGoals
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	defer conn.Close()
 
-		MATCH pathSucc = (root:Goal {run: 0, condition: 'post'})-[*0..]->(goal:Goal {run: 0, condition: 'post'})
-		WHERE NOT root.label IN failGoals AND NOT goal.label IN failGoals
-		RETURN pathSucc;
-		", "/tmp/export-differential-provenance", {format: "cypher-shell", cypherFormat: "create"})
-		YIELD time
-		RETURN time;
-	`
+	// Pull each successful run's consequent provenance once.
+	successProvs := make(map[uint]*provGraph)
 
 	diffDots := make([]*gographviz.Graph, len(failedRuns))
 	failedDots := make([]*gographviz.Graph, len(failedRuns))
 	missingEvents := make([][]*fi.Missing, len(failedRuns))
 
+	var excessDots []*gographviz.Graph
+	var excessEvents [][]*fi.Missing
+	if symmetric {
+		excessDots = make([]*gographviz.Graph, len(failedRuns))
+		excessEvents = make([][]*fi.Missing, len(failedRuns))
+	}
+
 	for i := range failedRuns {
 
-		diffRunID := 2000 + failedRuns[i]
+		successProv, pulled := successProvs[successRuns[i]]
+		if !pulled {
 
-		// Replace failed run in skeleton query.
-		exportQuery = strings.Replace(exportQuery, "###RUN###", fmt.Sprintf("%d", failedRuns[i]), -1)
-		_, err := n.Conn1.ExecNeo(exportQuery, nil)
-		if err != nil {
-			return nil, nil, nil, err
+			successProv, err = n.pullProvGraph(conn, KindRaw, successRuns[i], "post")
+			if err != nil {
+				return nil, nil, nil, nil, nil, err
+			}
+
+			successProvs[successRuns[i]] = successProv
 		}
 
-		// Replace run ID part of node ID in saved queries.
-		sedIDLong := fmt.Sprintf("s/`id`:\"run_0/`id`:\"run_%d/g", diffRunID)
-		cmd := exec.Command("sudo", "docker", "exec", "graphdb", "sed", "-i", sedIDLong, "/tmp/export-differential-provenance")
-		out, err := cmd.CombinedOutput()
+		failedProv, err := n.pullProvGraph(conn, KindRaw, failedRuns[i], "post")
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
-		if strings.TrimSpace(string(out)) != "" {
-			return nil, nil, nil, fmt.Errorf("Wrong return value from docker-compose exec sed diffprov run ID command: %s", out)
-		}
+		// Align both runs' provenance modulo time shifts.
+		alignment := alignProv(successProv, failedProv)
 
-		// Replace run ID in saved queries.
-		sedIDShort := fmt.Sprintf("s/`run`:0/`run`:%d/g", diffRunID)
-		cmd = exec.Command("sudo", "docker", "exec", "graphdb", "sed", "-i", sedIDShort, "/tmp/export-differential-provenance")
-		out, err = cmd.CombinedOutput()
-		if err != nil {
-			return nil, nil, nil, err
-		}
+		if symmetric {
+
+			// Keep all paths of the failed run whose start and
+			// end goals have no counterpart in the successful one.
+			excessProv := failedOnly(alignment, failedProv, failedRuns[i])
 
-		if strings.TrimSpace(string(out)) != "" {
-			return nil, nil, nil, fmt.Errorf("Wrong return value from docker-compose exec sed diffprov run ID command: %s", out)
+			// Import reverse difference graph as new one.
+			err = n.loadProv(conn, KindExcess, failedRuns[i], "post", excessProv.toProvData())
+			if err != nil {
+				return nil, nil, nil, nil, nil, err
+			}
+
+			excess := excessProv.missingLeaves()
+
+			excessDot, err := createExcessDot(excessProv.paths(), failedRuns[i], postProvDots[failedRuns[i]], excess)
+			if err != nil {
+				return nil, nil, nil, nil, nil, err
+			}
+
+			excessDots[i] = excessDot
+			excessEvents[i] = excess
 		}
 
-		// Import modified difference graph as new one.
-		_, err = n.Conn1.ExecNeo(`
-			CALL apoc.cypher.runFile("/tmp/export-differential-provenance", {statistics: false});
-		`, nil)
+		// Keep all paths of the successful run whose start
+		// and end goals have no counterpart in the failed
+		// run and replace run ID part of node IDs.
+		diffProv := successProv.between(func(goal *fi.Goal) bool {
+			return alignment.missing(goal.ID)
+		}).renamed(idPrefix(KindRaw, successRuns[i], "post"), idPrefix(KindDiff, failedRuns[i], "post"))
+
+		// Import difference graph as new one.
+		err = n.loadProv(conn, KindDiff, failedRuns[i], "post", diffProv.toProvData())
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		// Query differential provenance graph for leaves.
-		stmtLeaves, err := n.Conn1.PrepareNeo(`
-			MATCH path = (root:Goal {run: {run}, condition: "post"})-[*0..]->(:Rule {run: {run}, condition: "post"})-[*1]->(leaf:Goal {run: {run}, condition: "post"})
+		stmtLeaves, err := conn.PrepareNeo(`
+			MATCH path = (root:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: "post"})-[*0..]->(:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: "post"})-[*1]->(leaf:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: "post"})
 			WHERE NOT ()-->(root) AND NOT (leaf)-->()
 			WITH length(path) AS maxLen
 			ORDER BY maxLen DESC
 			LIMIT 1
 			WITH maxLen
 
-			MATCH path = (root:Goal {run: {run}, condition: "post"})-[*0..]->(rule:Rule {run: {run}, condition: "post"})-[*1]->(leaf:Goal {run: {run}, condition: "post"})
+			MATCH path = (root:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: "post"})-[*0..]->(rule:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: "post"})-[*1]->(leaf:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: "post"})
 			WHERE NOT ()-->(root) AND NOT (leaf)-->() AND length(path) = maxLen
 
 			WITH DISTINCT rule
-			MATCH (rule)-[*1]->(leaf:Goal {run: {run}, condition: "post"})
+			MATCH (rule)-[*1]->(leaf:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: "post"})
 			WITH rule, collect(leaf) AS leaves
 
 			RETURN rule, leaves;
 		`)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		leavesRaw, err := stmtLeaves.QueryNeo(map[string]interface{}{
-			"run": diffRunID,
+			"analysis": n.Analysis,
+			"kind":     string(KindDiff),
+			"run":      failedRuns[i],
 		})
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		leavesAll, _, err := leavesRaw.All()
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		missing := make([]*fi.Missing, len(leavesAll))
@@ -147,28 +187,30 @@ func (n *Neo4J) CreateNaiveDiffProv(symmetric bool, failedRuns []uint, successPo
 
 		err = leavesRaw.Close()
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		err = stmtLeaves.Close()
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		// Query for imported differential provenance.
-		stmtProv, err := n.Conn1.PrepareNeo(`
-			MATCH path = ({run: {run}, condition: "post"})-[:DUETO*1]->({run: {run}, condition: "post"})
+		stmtProv, err := conn.PrepareNeo(`
+			MATCH path = ({analysis: {analysis}, kind: {kind}, run: {run}, condition: "post"})-[:DUETO*1]->({analysis: {analysis}, kind: {kind}, run: {run}, condition: "post"})
 			RETURN path;
 		`)
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		edgesRaw, err := stmtProv.QueryNeo(map[string]interface{}{
-			"run": diffRunID,
+			"analysis": n.Analysis,
+			"kind":     string(KindDiff),
+			"run":      failedRuns[i],
 		})
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		diffEdges := make([]graph.Path, 0, 10)
@@ -179,7 +221,7 @@ func (n *Neo4J) CreateNaiveDiffProv(symmetric bool, failedRuns []uint, successPo
 
 			edgeRaw, _, err = edgesRaw.NextNeo()
 			if err != nil && err != io.EOF {
-				return nil, nil, nil, err
+				return nil, nil, nil, nil, nil, err
 			} else if err == nil {
 
 				// Type-assert raw edge into well-defined struct.
@@ -192,14 +234,16 @@ func (n *Neo4J) CreateNaiveDiffProv(symmetric bool, failedRuns []uint, successPo
 
 		err = edgesRaw.Close()
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		edgesRaw, err = stmtProv.QueryNeo(map[string]interface{}{
-			"run": failedRuns[i],
+			"analysis": n.Analysis,
+			"kind":     string(KindRaw),
+			"run":      failedRuns[i],
 		})
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		failedEdges := make([]graph.Path, 0, 10)
@@ -210,7 +254,7 @@ func (n *Neo4J) CreateNaiveDiffProv(symmetric bool, failedRuns []uint, successPo
 
 			edgeRaw, _, err = edgesRaw.NextNeo()
 			if err != nil && err != io.EOF {
-				return nil, nil, nil, err
+				return nil, nil, nil, nil, nil, err
 			} else if err == nil {
 
 				// Type-assert raw edge into well-defined struct.
@@ -222,14 +266,14 @@ func (n *Neo4J) CreateNaiveDiffProv(symmetric bool, failedRuns []uint, successPo
 		}
 
 		// Pass to DOT string generator.
-		diffDot, failedDot, err := createDiffDot(diffRunID, diffEdges, failedRuns[i], failedEdges, 0, successPostProv, missing)
+		diffDot, failedDot, err := createDiffDot(diffEdges, failedRuns[i], failedEdges, successRuns[i], postProvDots[successRuns[i]], missing, alignment.renamed(idPrefix(KindRaw, successRuns[i], "post"), idPrefix(KindDiff, failedRuns[i], "post")))
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		err = stmtProv.Close()
 		if err != nil {
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, nil, err
 		}
 
 		diffDots[i] = diffDot
@@ -239,5 +283,5 @@ func (n *Neo4J) CreateNaiveDiffProv(symmetric bool, failedRuns []uint, successPo
 
 	fmt.Printf("done\n\n")
 
-	return diffDots, failedDots, missingEvents, nil
+	return diffDots, failedDots, missingEvents, excessDots, excessEvents, nil
 }
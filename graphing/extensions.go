@@ -10,7 +10,13 @@ import (
 // Functions.
 
 // GenerateExtensions
-func (n *Neo4J) GenerateExtensions() (bool, []string, error) {
+func (n *Neo4J) GenerateExtensions(successRun uint) (bool, []string, error) {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return false, nil, err
+	}
+	defer conn.Close()
 
 	// Track if all runs achieve the antecedent.
 	allAchievedPre := true
@@ -22,11 +28,13 @@ func (n *Neo4J) GenerateExtensions() (bool, []string, error) {
 	rulesState := make(map[string]string)
 
 	// Query for antecedent achievement per run.
-	preAchievedRows, err := n.Conn1.QueryNeo(`
-		MATCH (pre:Goal {condition: "pre", table: "pre", condition_holds: true})
-		WHERE pre.run < 1000
+	preAchievedRows, err := conn.QueryNeo(`
+		MATCH (pre:Goal {analysis: {analysis}, kind: {kind}, condition: "pre", table: "pre", condition_holds: true})
 		RETURN collect(pre) AS pres;
-	`, nil)
+	`, map[string]interface{}{
+		"analysis": n.Analysis,
+		"kind":     string(KindRaw),
+	})
 	if err != nil {
 		return false, nil, err
 	}
@@ -57,14 +65,18 @@ func (n *Neo4J) GenerateExtensions() (bool, []string, error) {
 	if !allAchievedPre {
 
 		// In case not all runs achieved the antecedent,
-		// we query the successful (first) run and collect
-		// all network events.
+		// we query the successful run and collect all
+		// network events.
 
-		asyncEventsRows, err := n.Conn1.QueryNeo(`
-			MATCH (r:Rule {run: 0, condition: "pre", type: "async"})
-			WHERE (:Goal {run: 0, condition: "pre", condition_holds: true})-[*1]->(r)-[*1]->(:Goal {run: 0, condition: "pre", condition_holds: false})-[*1]->(:Rule {run: 0, condition: "pre"}) OR (:Goal {run: 0, condition: "pre", condition_holds: false})-[*1]->(r)
+		asyncEventsRows, err := conn.QueryNeo(`
+			MATCH (r:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: "pre", type: "async"})
+			WHERE (:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: "pre", condition_holds: true})-[*1]->(r)-[*1]->(:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: "pre", condition_holds: false})-[*1]->(:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: "pre"}) OR (:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: "pre", condition_holds: false})-[*1]->(r)
 			RETURN r;
-		`, nil)
+		`, map[string]interface{}{
+			"analysis": n.Analysis,
+			"kind":     string(KindRaw),
+			"run":      successRun,
+		})
 		if err != nil {
 			return false, nil, err
 		}
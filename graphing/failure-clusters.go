@@ -0,0 +1,114 @@
+package graphing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Functions.
+
+// sortedCopy returns the strings sorted, without
+// modifying the slice passed in.
+func sortedCopy(strs []string) []string {
+
+	sorted := make([]string, len(strs))
+	copy(sorted, strs)
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// missingSignature describes missing event m modulo time:
+// its rule's table and the time patterns of its goals.
+func missingSignature(m *fi.Missing) string {
+
+	patterns := make([]string, len(m.Goals))
+	for i := range m.Goals {
+		patterns[i] = timePattern(m.Goals[i])
+	}
+	sort.Strings(patterns)
+
+	return fmt.Sprintf("%s: %s", m.Rule.Table, strings.Join(patterns, ", "))
+}
+
+// failureSignature extracts the failure signature of run.
+func failureSignature(run *fi.Run) *fi.FailureSignature {
+
+	sig := &fi.FailureSignature{
+		MissingEvents:     make([]string, 0, len(run.MissingEvents)),
+		InterProtoMissing: sortedCopy(run.InterProtoMissing),
+		UnionProtoMissing: sortedCopy(run.UnionProtoMissing),
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range run.MissingEvents {
+
+		event := missingSignature(m)
+		if !seen[event] {
+			seen[event] = true
+			sig.MissingEvents = append(sig.MissingEvents, event)
+		}
+	}
+	sort.Strings(sig.MissingEvents)
+
+	if run.FailureSpec != nil {
+
+		if run.FailureSpec.Crashes != nil {
+			sig.Crashes = len(*run.FailureSpec.Crashes)
+		}
+
+		if run.FailureSpec.Omissions != nil {
+			sig.Omissions = len(*run.FailureSpec.Omissions)
+		}
+	}
+
+	return sig
+}
+
+// signatureKey renders the signature for comparison.
+func signatureKey(sig *fi.FailureSignature) string {
+
+	return fmt.Sprintf("%q|%q|%q|%d|%d", sig.MissingEvents, sig.InterProtoMissing, sig.UnionProtoMissing, sig.Crashes, sig.Omissions)
+}
+
+// ClusterFailures groups the failed runs by their failure
+// signature, after differential provenance and prototypes
+// have been attached to them. Clusters are numbered from 1
+// in the order of their earliest member, which represents
+// the cluster.
+func ClusterFailures(runs []*fi.Run, failedRuns []uint) []*fi.FailureCluster {
+
+	fmt.Printf("Clustering failed runs by failure signature... ")
+
+	clusters := make([]*fi.FailureCluster, 0, 2)
+	byKey := make(map[string]*fi.FailureCluster)
+
+	for _, iter := range failedRuns {
+
+		sig := failureSignature(runs[iter])
+		key := signatureKey(sig)
+
+		c, found := byKey[key]
+		if !found {
+
+			c = &fi.FailureCluster{
+				ID:             len(clusters) + 1,
+				Representative: iter,
+				Members:        make([]uint, 0, 4),
+				Signature:      sig,
+			}
+
+			byKey[key] = c
+			clusters = append(clusters, c)
+		}
+
+		c.Members = append(c.Members, iter)
+	}
+
+	fmt.Printf("done\n\n")
+
+	return clusters
+}
@@ -0,0 +1,125 @@
+package graphing
+
+import (
+	"reflect"
+	"testing"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Functions.
+
+func TestSignatureKey(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		a     *fi.FailureSignature
+		b     *fi.FailureSignature
+		equal bool
+	}{
+		{
+			name:  "same signature",
+			a:     &fi.FailureSignature{MissingEvents: []string{"log: log(b, t+0)"}, Crashes: 1},
+			b:     &fi.FailureSignature{MissingEvents: []string{"log: log(b, t+0)"}, Crashes: 1},
+			equal: true,
+		},
+		{
+			name:  "nil and empty lists",
+			a:     &fi.FailureSignature{MissingEvents: []string{}},
+			b:     &fi.FailureSignature{},
+			equal: true,
+		},
+		{
+			name: "separators within events",
+			a:    &fi.FailureSignature{MissingEvents: []string{"log: a, b"}},
+			b:    &fi.FailureSignature{MissingEvents: []string{"log: a", "b"}},
+		},
+		{
+			name: "events in other list",
+			a:    &fi.FailureSignature{InterProtoMissing: []string{"log"}},
+			b:    &fi.FailureSignature{UnionProtoMissing: []string{"log"}},
+		},
+		{
+			name: "crashes and omissions",
+			a:    &fi.FailureSignature{Crashes: 1},
+			b:    &fi.FailureSignature{Omissions: 1},
+		},
+	}
+
+	for _, tt := range tests {
+
+		if (signatureKey(tt.a) == signatureKey(tt.b)) != tt.equal {
+			t.Errorf("%s: keys '%s' and '%s' equal is %t", tt.name, signatureKey(tt.a), signatureKey(tt.b), !tt.equal)
+		}
+	}
+}
+
+func TestClusterFailures(t *testing.T) {
+
+	// missing builds a missing log entry at b at time.
+	missing := func(time string) *fi.Missing {
+
+		goal := testGoal("g", "log", time, "b, data, "+time)
+
+		return &fi.Missing{
+			Rule:  &fi.Rule{ID: "r", Label: "log", Table: "log", Type: "async"},
+			Goals: []*fi.Goal{&goal},
+		}
+	}
+
+	// failed builds a failed run missing the supplied
+	// events and suffering the supplied crashes.
+	failed := func(iter uint, crashes int, events ...*fi.Missing) *fi.Run {
+
+		crashed := make([]fi.CrashFailure, crashes)
+
+		return &fi.Run{
+			Iteration:         iter,
+			Status:            "failure",
+			FailureSpec:       &fi.FailureSpec{Crashes: &crashed},
+			MissingEvents:     events,
+			InterProtoMissing: []string{"log", "bcast"},
+			UnionProtoMissing: []string{"bcast", "log"},
+		}
+	}
+
+	runs := []*fi.Run{
+		{Iteration: 0, Status: "success"},
+		failed(1, 0, missing("2")),
+		failed(2, 1, missing("2")),
+		// Same event one tick later, listed twice.
+		failed(3, 0, missing("3"), missing("3")),
+		failed(4, 0),
+	}
+
+	clusters := ClusterFailures(runs, []uint{1, 2, 3, 4})
+
+	expected := []struct {
+		representative uint
+		members        []uint
+	}{
+		{1, []uint{1, 3}},
+		{2, []uint{2}},
+		{4, []uint{4}},
+	}
+
+	if len(clusters) != len(expected) {
+		t.Fatalf("found %d clusters, expected %d", len(clusters), len(expected))
+	}
+
+	for i, c := range clusters {
+
+		if (c.ID != (i + 1)) || (c.Representative != expected[i].representative) || !reflect.DeepEqual(c.Members, expected[i].members) {
+			t.Errorf("cluster %d is %d represented by %d with %v, expected represented by %d with %v", i, c.ID, c.Representative, c.Members, expected[i].representative, expected[i].members)
+		}
+	}
+
+	sig := clusters[0].Signature
+	if !reflect.DeepEqual(sig.MissingEvents, []string{"log: log(b, data, t+0)"}) || !reflect.DeepEqual(sig.InterProtoMissing, []string{"bcast", "log"}) {
+		t.Errorf("signature is %+v, expected sorted events modulo time", *sig)
+	}
+
+	if !reflect.DeepEqual(runs[1].InterProtoMissing, []string{"log", "bcast"}) {
+		t.Errorf("clustering reordered the run's missing rules to %v", runs[1].InterProtoMissing)
+	}
+}
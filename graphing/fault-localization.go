@@ -0,0 +1,173 @@
+package graphing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Structs.
+
+// spectrumEntry counts the failed and
+// successful runs covering one element.
+type spectrumEntry struct {
+	kind   string
+	failed int
+	passed int
+}
+
+// Functions.
+
+// coverage collects the elements a run's provenance graph
+// covers: the tables of all rules that fired, keyed as
+// "rule", and the nodes these rules derived goals at,
+// keyed as "node".
+func (g *provGraph) coverage(covered map[[2]string]bool) {
+
+	for ruleID, rule := range g.rules {
+
+		covered[[2]string{"rule", rule.Table}] = true
+
+		for _, headID := range g.preds[ruleID] {
+
+			if head := g.goals[headID]; head != nil {
+				covered[[2]string{"node", goalReceiver(head.Label, head.Table)}] = true
+			}
+		}
+	}
+}
+
+// ochiai computes the Ochiai coefficient of an element
+// covered by ef of f failed runs.
+func ochiai(ef int, ep int, f int) float64 {
+
+	if ef == 0 {
+		return 0.0
+	}
+
+	return float64(ef) / math.Sqrt(float64(f*(ef+ep)))
+}
+
+// tarantula computes the Tarantula score of an element
+// covered by ef of f failed and ep of p successful runs.
+func tarantula(ef int, ep int, f int, p int) float64 {
+
+	if (ef == 0) || (f == 0) {
+		return 0.0
+	}
+
+	failRatio := float64(ef) / float64(f)
+
+	passRatio := 0.0
+	if p > 0 {
+		passRatio = float64(ep) / float64(p)
+	}
+
+	return failRatio / (failRatio + passRatio)
+}
+
+// localizeFaults computes the suspiciousness of all rule
+// tables and nodes from their coverage of successful and
+// failed runs, pulling antecedent and consequent provenance
+// via prov. Results are sorted by descending Ochiai, then
+// Tarantula score.
+func localizeFaults(successRuns []uint, failedRuns []uint, prov func(run uint, condition string) (*provGraph, error)) ([]*fi.Suspiciousness, error) {
+
+	fmt.Printf("Localizing faults from rule spectra... ")
+
+	spectrum := make(map[[2]string]*spectrumEntry)
+
+	count := func(runs []uint, failed bool) error {
+
+		for _, run := range runs {
+
+			covered := make(map[[2]string]bool)
+			for _, condition := range []string{"pre", "post"} {
+
+				g, err := prov(run, condition)
+				if err != nil {
+					return err
+				}
+
+				g.coverage(covered)
+			}
+
+			for element := range covered {
+
+				entry, found := spectrum[element]
+				if !found {
+					entry = &spectrumEntry{kind: element[0]}
+					spectrum[element] = entry
+				}
+
+				if failed {
+					entry.failed++
+				} else {
+					entry.passed++
+				}
+			}
+		}
+
+		return nil
+	}
+
+	err := count(successRuns, false)
+	if err != nil {
+		return nil, err
+	}
+
+	err = count(failedRuns, true)
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make([]*fi.Suspiciousness, 0, len(spectrum))
+	for element, entry := range spectrum {
+
+		scores = append(scores, &fi.Suspiciousness{
+			Element:    element[1],
+			Kind:       entry.kind,
+			Ochiai:     math.Round(ochiai(entry.failed, entry.passed, len(failedRuns))*1000) / 1000,
+			Tarantula:  math.Round(tarantula(entry.failed, entry.passed, len(failedRuns), len(successRuns))*1000) / 1000,
+			FailedRuns: entry.failed,
+			PassedRuns: entry.passed,
+		})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+
+		if scores[i].Ochiai != scores[j].Ochiai {
+			return scores[i].Ochiai > scores[j].Ochiai
+		}
+
+		if scores[i].Tarantula != scores[j].Tarantula {
+			return scores[i].Tarantula > scores[j].Tarantula
+		}
+
+		if scores[i].Kind != scores[j].Kind {
+			return scores[i].Kind > scores[j].Kind
+		}
+
+		return scores[i].Element < scores[j].Element
+	})
+
+	fmt.Printf("done\n\n")
+
+	return scores, nil
+}
+
+// LocalizeFaults
+func (n *Neo4J) LocalizeFaults(successRuns []uint, failedRuns []uint) ([]*fi.Suspiciousness, error) {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return localizeFaults(successRuns, failedRuns, func(run uint, condition string) (*provGraph, error) {
+		return n.pullProvGraph(conn, KindRaw, run, condition)
+	})
+}
@@ -0,0 +1,40 @@
+package graphing
+
+import (
+	"math"
+	"testing"
+)
+
+// Functions.
+
+func TestSuspiciousness(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		ef        int
+		ep        int
+		f         int
+		p         int
+		ochiai    float64
+		tarantula float64
+	}{
+		{"no failed runs", 0, 2, 0, 2, 0.0, 0.0},
+		{"no successful runs", 2, 0, 2, 0, 1.0, 1.0},
+		{"not covered by failed runs", 0, 1, 2, 2, 0.0, 0.0},
+		{"only covered by failed runs", 1, 0, 2, 2, math.Sqrt(0.5), 1.0},
+		{"covered by all failed runs", 2, 0, 2, 2, 1.0, 1.0},
+		{"covered by all runs", 2, 2, 2, 2, math.Sqrt(0.5), 0.5},
+		{"covered mostly by successful runs", 1, 3, 2, 4, 0.5 / math.Sqrt(2), 0.4},
+	}
+
+	for _, tt := range tests {
+
+		if o := ochiai(tt.ef, tt.ep, tt.f); math.Abs(o-tt.ochiai) > 1e-9 {
+			t.Errorf("%s: Ochiai is %f, expected %f", tt.name, o, tt.ochiai)
+		}
+
+		if ta := tarantula(tt.ef, tt.ep, tt.f, tt.p); math.Abs(ta-tt.tarantula) > 1e-9 {
+			t.Errorf("%s: Tarantula is %f, expected %f", tt.name, ta, tt.tarantula)
+		}
+	}
+}
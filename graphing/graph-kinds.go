@@ -0,0 +1,44 @@
+package graphing
+
+import (
+	"fmt"
+)
+
+// Types.
+
+// GraphKind names the namespace a provenance graph lives
+// in. Together with run and condition it identifies one
+// graph: the raw one loaded from the fault injector or
+// one of the graphs derived from it.
+type GraphKind string
+
+// Constants.
+
+const (
+	// KindRaw is provenance as emitted by the fault injector.
+	KindRaw GraphKind = "raw"
+
+	// KindClean is simplified (cleaned-up) provenance.
+	KindClean GraphKind = "clean"
+
+	// KindDiff is differential provenance of a failed run.
+	KindDiff GraphKind = "diff"
+
+	// KindExcess is reverse differential provenance of a
+	// failed run: derivations absent from the successful run.
+	KindExcess GraphKind = "excess"
+)
+
+// Functions.
+
+// idPrefix returns the prefix of all node IDs in the
+// graph identified by kind, run, and condition. Raw
+// graphs keep the IDs assigned by the fault injector.
+func idPrefix(kind GraphKind, run uint, condition string) string {
+
+	if kind == KindRaw {
+		return fmt.Sprintf("run_%d_%s_", run, condition)
+	}
+
+	return fmt.Sprintf("%s_run_%d_%s_", kind, run, condition)
+}
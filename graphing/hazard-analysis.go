@@ -2,78 +2,499 @@ package graphing
 
 import (
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 
-	"io/ioutil"
-	"path/filepath"
-
 	"github.com/awalterschulze/gographviz"
+	fi "github.com/numbleroot/nemo/faultinjectors"
 )
 
 // Functions.
 
-// CreateHazardAnalysis
-func (n *Neo4J) CreateHazardAnalysis(faultInjOut string) ([]*gographviz.Graph, error) {
+// spaceTimeFromRun synthesises the space-time diagram of
+// a run from its failure specification and messages, for
+// fault injectors that do not emit one. Each node gets a
+// process line of one vertex per time step up to its
+// crash, which ends in a crash marker. Delivered messages
+// are drawn as blue arrows, messages lost to an omission
+// or to the crash of their receiver as dashed red ones.
+func spaceTimeFromRun(run *fi.Run) (*gographviz.Graph, error) {
 
-	fmt.Printf("Running hazard window analysis... ")
+	spaceTimeGraph := gographviz.NewGraph()
 
-	dots := make([]*gographviz.Graph, len(n.Runs))
+	err := spaceTimeGraph.SetName("spacetime")
+	if err != nil {
+		return nil, err
+	}
 
-	for i := range n.Runs {
+	err = spaceTimeGraph.SetDir(true)
+	if err != nil {
+		return nil, err
+	}
 
-		// Space-time file name in fault injector directory.
-		fiSpaceTime := filepath.Join(faultInjOut, fmt.Sprintf("run_%d_spacetime.dot", n.Runs[i].Iteration))
+	if (run.FailureSpec == nil) || (run.FailureSpec.Nodes == nil) {
+		return spaceTimeGraph, nil
+	}
 
-		// Load current space-time diagram.
-		spaceTimeDotBytes, err := ioutil.ReadFile(fiSpaceTime)
+	spec := run.FailureSpec
+
+	crashTimes := make(map[string]uint)
+	if spec.Crashes != nil {
+		for _, crash := range *spec.Crashes {
+			crashTimes[crash.Node] = crash.Time
+		}
+	}
+
+	omitted := make(map[fi.MessageLoss]bool)
+	if spec.Omissions != nil {
+		for _, omission := range *spec.Omissions {
+			omitted[omission] = true
+		}
+	}
+
+	nodeName := func(node string, t uint) string {
+		return fmt.Sprintf("\"proc_%s_%d\"", node, t)
+	}
+
+	crashName := func(node string) string {
+		return fmt.Sprintf("\"crash_%s\"", node)
+	}
+
+	// Align all vertices of the same time step.
+	for t := uint(1); t <= spec.EOT; t++ {
+
+		err := spaceTimeGraph.AddSubGraph("spacetime", fmt.Sprintf("time_%d", t), map[string]string{
+			"rank": "same",
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	exists := make(map[string]bool)
+
+	for _, node := range *spec.Nodes {
+
+		lastTime := spec.EOT
+		crashTime, crashed := crashTimes[node]
+		if crashed && (crashTime < lastTime) {
+			lastTime = crashTime
+		}
+
+		for t := uint(1); t <= lastTime; t++ {
+
+			err := spaceTimeGraph.AddNode(fmt.Sprintf("time_%d", t), nodeName(node, t), map[string]string{
+				"label": fmt.Sprintf("\"%s@%d\"", node, t),
+			})
+			if err != nil {
+				return nil, err
+			}
+			exists[nodeName(node, t)] = true
+
+			if t > 1 {
+
+				err := spaceTimeGraph.AddEdge(nodeName(node, (t-1)), nodeName(node, t), true, map[string]string{
+					"weight": "10",
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if !crashed {
+			continue
+		}
+
+		// End the process line in a crash marker.
+		err := spaceTimeGraph.AddNode("spacetime", crashName(node), map[string]string{
+			"label":     fmt.Sprintf("\"%s crashed @ %d\"", node, crashTime),
+			"shape":     "\"octagon\"",
+			"style":     "\"filled\"",
+			"color":     "\"black\"",
+			"fillcolor": "\"black\"",
+			"fontcolor": "\"white\"",
+		})
 		if err != nil {
 			return nil, err
 		}
+		exists[crashName(node)] = true
+
+		if lastTime > 0 {
+
+			err := spaceTimeGraph.AddEdge(nodeName(node, lastTime), crashName(node), true, map[string]string{
+				"style":  "\"dashed\"",
+				"weight": "10",
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	lost := func(label string) map[string]string {
+
+		return map[string]string{
+			"color":     "\"red\"",
+			"fontcolor": "\"red\"",
+			"style":     "\"dashed\"",
+			"arrowhead": "\"tee\"",
+			"label":     fmt.Sprintf("\"%s\"", label),
+		}
+	}
+
+	drawn := make(map[fi.MessageLoss]bool)
+
+	for _, msg := range run.Messages {
+
+		if msg == nil {
+			continue
+		}
+
+		loss := fi.MessageLoss{
+			From: msg.SendNode,
+			To:   msg.RecvNode,
+			Time: msg.SendTime,
+		}
 
-		// Read DOT data.
-		spaceTimeGraph, err := gographviz.Read(spaceTimeDotBytes)
+		from := nodeName(msg.SendNode, msg.SendTime)
+		to := nodeName(msg.RecvNode, msg.RecvTime)
+		attrs := map[string]string{
+			"color": "\"blue\"",
+			"label": fmt.Sprintf("\"%s\"", msg.Content),
+		}
+
+		if omitted[loss] {
+			attrs = lost(msg.Content)
+			drawn[loss] = true
+		} else if !exists[to] && exists[crashName(msg.RecvNode)] {
+			to = crashName(msg.RecvNode)
+			attrs = lost(msg.Content)
+		}
+
+		if !exists[from] || !exists[to] {
+			continue
+		}
+
+		err := spaceTimeGraph.AddEdge(from, to, true, attrs)
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	// Draw omissions of messages not listed
+	// as arriving in the next time step.
+	if spec.Omissions != nil {
+		for _, omission := range *spec.Omissions {
+
+			from := nodeName(omission.From, omission.Time)
+			to := nodeName(omission.To, (omission.Time + 1))
+
+			if drawn[omission] || !exists[from] || !exists[to] {
+				continue
+			}
+			drawn[omission] = true
+
+			err := spaceTimeGraph.AddEdge(from, to, true, lost("lost"))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return spaceTimeGraph, nil
+}
+
+// spaceTimeProc returns the process and time step a
+// node of a space-time diagram stands for. Nodes are
+// named proc_<process>_<time>, or labelled <process>@<time>.
+func spaceTimeProc(node *gographviz.Node) (string, string) {
+
+	label := strings.Trim(node.Attrs["label"], "\"")
+	if at := strings.LastIndex(label, "@"); at != -1 {
+		return label[:at], label[(at + 1):]
+	}
+
+	name := strings.Trim(node.Name, "\"")
+
+	last := strings.LastIndex(name, "_")
+	if last == -1 {
+		return "", name
+	}
+
+	return strings.TrimPrefix(name[:last], "proc_"), name[(last + 1):]
+}
+
+// nodeHolds returns per node when a condition holds.
+// Runs lacking this information hold the condition at
+// all nodes at the time steps in times.
+func nodeHolds(nodes map[string]map[string]bool, times map[string]bool) map[string]map[string]bool {
+
+	if nodes != nil {
+		return nodes
+	}
+
+	return map[string]map[string]bool{"": times}
+}
+
+// holdsAt reports whether a condition holding per node
+// as in holds does so at process proc at time step t.
+// Conditions not defined over node-local state, i.e.,
+// collected under the empty node name, hold everywhere.
+func holdsAt(holds map[string]map[string]bool, proc string, t string) bool {
+	return holds[proc][t] || holds[""][t]
+}
+
+// firstHold returns the earliest time step at which
+// a condition holds at any time in times, or -1.
+func firstHold(times map[string]bool) int {
+
+	first := -1
+
+	for t := range times {
+
+		step, err := strconv.Atoi(t)
+		if err != nil {
+			continue
+		}
+
+		if (first == -1) || (step < first) {
+			first = step
+		}
+	}
+
+	return first
+}
+
+// hazardWindow determines per process the time steps at
+// which the antecedent holds at this process before the
+// consequent first holds there. Steps at which the
+// antecedent stopped holding are no part of the window,
+// so it may consist of several intervals. An antecedent not
+// defined over node-local state, i.e., collected under the
+// empty node name, counts as held at every process. All
+// nodes of the run and all processes the antecedent held
+// at are considered, whether or not the consequent ever
+// holds at them.
+func hazardWindow(run *fi.Run) map[string]map[string]bool {
+
+	window := make(map[string]map[string]bool)
+
+	if run.FailureSpec == nil {
+		return window
+	}
+
+	preHolds := nodeHolds(run.NodesPreHolds, run.TimePreHolds)
+	postHolds := nodeHolds(run.NodesPostHolds, run.TimePostHolds)
+
+	procs := make(map[string]bool)
+	if run.FailureSpec.Nodes != nil {
+		for _, node := range *run.FailureSpec.Nodes {
+			procs[node] = true
+		}
+	}
+
+	for proc := range preHolds {
+		if proc != "" {
+			procs[proc] = true
+		}
+	}
+
+	for proc := range procs {
+
+		// The window closes once the consequent holds here.
+		closed := int(run.FailureSpec.EOT) + 1
+		for _, node := range []string{proc, ""} {
+
+			first := firstHold(postHolds[node])
+			if (first != -1) && (first < closed) {
+				closed = first
+			}
+		}
+
+		// It covers the steps the antecedent held here before.
+		for _, node := range []string{proc, ""} {
+			for t := range preHolds[node] {
+
+				step, err := strconv.Atoi(t)
+				if (err != nil) || (step >= closed) {
+					continue
+				}
+
+				if window[proc] == nil {
+					window[proc] = make(map[string]bool)
+				}
+
+				window[proc][t] = true
+			}
+		}
+	}
+
+	return window
+}
+
+// hazardIntervals turns the hazard window of each process
+// of run into maximal intervals of consecutive time steps
+// and correlates them with the crashes and omissions of
+// run that hit the process before the interval ended.
+func hazardIntervals(run *fi.Run, window map[string]map[string]bool) []*fi.HazardWindow {
+
+	postHolds := nodeHolds(run.NodesPostHolds, run.TimePostHolds)
+
+	procs := make([]string, 0, len(window))
+	for proc := range window {
+		procs = append(procs, proc)
+	}
+	sort.Strings(procs)
+
+	intervals := make([]*fi.HazardWindow, 0, len(procs))
+
+	for _, proc := range procs {
+
+		steps := make([]int, 0, len(window[proc]))
+		for t := range window[proc] {
+
+			step, err := strconv.Atoi(t)
+			if err == nil {
+				steps = append(steps, step)
+			}
+		}
+		sort.Ints(steps)
+
+		for j := 0; j < len(steps); {
+
+			// Extend interval over consecutive time steps.
+			k := j
+			for ((k + 1) < len(steps)) && (steps[(k+1)] == (steps[k] + 1)) {
+				k++
+			}
+
+			interval := &fi.HazardWindow{
+				Node:   proc,
+				Start:  uint(steps[j]),
+				End:    uint(steps[k]),
+				Length: uint(k - j + 1),
+				Closed: holdsAt(postHolds, proc, strconv.Itoa(steps[k]+1)),
+			}
+
+			if (run.FailureSpec != nil) && (run.FailureSpec.Crashes != nil) {
+				for _, crash := range *run.FailureSpec.Crashes {
+
+					if (crash.Node == proc) && (crash.Time <= interval.End) {
+						interval.Crashes = append(interval.Crashes, crash)
+					}
+				}
+			}
+
+			if (run.FailureSpec != nil) && (run.FailureSpec.Omissions != nil) {
+				for _, omission := range *run.FailureSpec.Omissions {
+
+					if ((omission.From == proc) || (omission.To == proc)) && (omission.Time <= interval.End) {
+						interval.Omissions = append(interval.Omissions, omission)
+					}
+				}
+			}
+
+			intervals = append(intervals, interval)
+			j = k + 1
+		}
+	}
+
+	return intervals
+}
+
+// createHazardAnalysis colours the space-time diagrams
+// Molly emitted for each run according to when and at
+// which process antecedent and consequent hold, and the
+// hazard window of each process. Runs without an emitted
+// diagram are drawn from their failure specification.
+// It also returns the hazard windows of each run as
+// intervals correlated with faults.
+func createHazardAnalysis(runs []*fi.Run, faultInjOut fi.FS) ([]*gographviz.Graph, [][]*fi.HazardWindow, error) {
+
+	fmt.Printf("Running hazard window analysis... ")
+
+	dots := make([]*gographviz.Graph, len(runs))
+	windows := make([][]*fi.HazardWindow, len(runs))
+
+	for i := range runs {
+
+		// Space-time file name in fault injector output.
+		fiSpaceTime := fmt.Sprintf("run_%d_spacetime.dot", runs[i].Iteration)
+
+		var spaceTimeGraph *gographviz.Graph
+
+		// Load current space-time diagram.
+		spaceTimeDotBytes, err := fi.ReadFile(faultInjOut, fiSpaceTime)
+		if os.IsNotExist(err) {
+
+			spaceTimeGraph, err = spaceTimeFromRun(runs[i])
+			if err != nil {
+				return nil, nil, err
+			}
+		} else if err != nil {
+			return nil, nil, err
+		} else {
+
+			// Read DOT data.
+			spaceTimeGraph, err = gographviz.Read(spaceTimeDotBytes)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		preHolds := nodeHolds(runs[i].NodesPreHolds, runs[i].TimePreHolds)
+		postHolds := nodeHolds(runs[i].NodesPostHolds, runs[i].TimePostHolds)
+		window := hazardWindow(runs[i])
+		windows[i] = hazardIntervals(runs[i], window)
 
 		for j := range spaceTimeGraph.Nodes.Nodes {
 
-			spaceTimeGraph.Nodes.Nodes[j].Attrs.Extend(map[gographviz.Attr]string{
+			node := spaceTimeGraph.Nodes.Nodes[j]
+
+			// Crash markers keep their colour.
+			if strings.HasPrefix(strings.Trim(node.Name, "\""), "crash_") {
+				continue
+			}
+
+			node.Attrs.Extend(map[gographviz.Attr]string{
 				"style":     "\"solid, filled\"",
 				"color":     "\"lightgrey\"",
 				"fillcolor": "\"lightgrey\"",
 			})
 
-			// Split into naming and time parts.
-			nameParts := strings.Split(spaceTimeGraph.Nodes.Nodes[j].Name, "_")
-
-			// Possibly selecting the time of the node here.
-			// If this is not actually the time, it does not
-			// pose a problem as our map below only works on
-			// actual timesteps.
-			nodeTime := nameParts[(len(nameParts) - 1)]
+			// Determine process and time of the node. If
+			// this is not actually the time, it does not
+			// pose a problem as our maps below only work
+			// on actual timesteps.
+			proc, nodeTime := spaceTimeProc(node)
 
-			// TODO: If Pre() is not specified over only
-			//       one global column, but rather over
-			//       node-individual local state, we have
-			//       to proceed differently here!
-			_, preHolds := n.Runs[i].TimePreHolds[nodeTime]
-			if preHolds {
+			// Only highlight the process that actually
+			// satisfied the antecedent.
+			if holdsAt(preHolds, proc, nodeTime) {
 
-				spaceTimeGraph.Nodes.Nodes[j].Attrs.Extend(map[gographviz.Attr]string{
+				node.Attrs.Extend(map[gographviz.Attr]string{
 					"color":     "\"firebrick\"",
 					"fillcolor": "\"firebrick\"",
 				})
 			}
 
-			// TODO: If Post() is not specified over only
-			//       one global column, but rather over
-			//       node-individual local state, we have
-			//       to proceed differently here!
-			_, postHolds := n.Runs[i].TimePostHolds[nodeTime]
-			if postHolds {
+			// Shade time steps in this process' hazard
+			// window, all of which satisfy the antecedent.
+			if window[proc][nodeTime] {
 
-				spaceTimeGraph.Nodes.Nodes[j].Attrs.Extend(map[gographviz.Attr]string{
+				node.Attrs.Extend(map[gographviz.Attr]string{
+					"color":     "\"firebrick\"",
+					"fillcolor": "\"lightsalmon\"",
+				})
+			}
+
+			// Likewise for the consequent.
+			if holdsAt(postHolds, proc, nodeTime) {
+
+				node.Attrs.Extend(map[gographviz.Attr]string{
 					"fillcolor": "\"deepskyblue\"",
 				})
 			}
@@ -84,5 +505,10 @@ func (n *Neo4J) CreateHazardAnalysis(faultInjOut string) ([]*gographviz.Graph, e
 
 	fmt.Printf("done\n\n")
 
-	return dots, nil
+	return dots, windows, nil
+}
+
+// CreateHazardAnalysis
+func (n *Neo4J) CreateHazardAnalysis(faultInjOut fi.FS) ([]*gographviz.Graph, [][]*fi.HazardWindow, error) {
+	return createHazardAnalysis(n.Runs, faultInjOut)
 }
@@ -0,0 +1,166 @@
+package graphing
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Functions.
+
+// testHolds builds the time steps per node at
+// which a condition holds.
+func testHolds(holds map[string][]string) map[string]map[string]bool {
+
+	times := make(map[string]map[string]bool)
+	for node := range holds {
+
+		times[node] = make(map[string]bool)
+		for _, t := range holds[node] {
+			times[node][t] = true
+		}
+	}
+
+	return times
+}
+
+func TestHazardWindowWithoutConsequent(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "nemo-hazard")
+	if err != nil {
+		t.Fatalf("creating directory failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fsys, err := fi.OpenFS(dir)
+	if err != nil {
+		t.Fatalf("opening directory failed: %v", err)
+	}
+
+	// No run ever satisfies the consequent.
+	runs := make([]*fi.Run, 2)
+	for i := range runs {
+
+		runs[i] = &fi.Run{
+			Iteration: uint(i),
+			Status:    "failure",
+			FailureSpec: &fi.FailureSpec{
+				EOT:   4,
+				Nodes: &[]string{"a", "b"},
+			},
+			NodesPreHolds:  testHolds(map[string][]string{"a": {"2", "3", "4"}}),
+			NodesPostHolds: testHolds(map[string][]string{}),
+		}
+	}
+
+	_, windows, err := createHazardAnalysis(runs, fsys)
+	if err != nil {
+		t.Fatalf("hazard analysis failed: %v", err)
+	}
+
+	for i := range runs {
+
+		expected := []*fi.HazardWindow{
+			{Node: "a", Start: 2, End: 4, Length: 3},
+		}
+
+		if !reflect.DeepEqual(windows[i], expected) {
+			t.Errorf("run %d: windows are %v, expected %v", i, windows[i], expected)
+		}
+	}
+}
+
+func TestHazardWindow(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		spec      *fi.FailureSpec
+		nodesPre  map[string][]string
+		timesPre  []string
+		nodesPost map[string][]string
+		windows   []*fi.HazardWindow
+	}{
+		{
+			name:      "node-local antecedent",
+			spec:      &fi.FailureSpec{EOT: 4, Nodes: &[]string{"a", "b"}},
+			nodesPre:  map[string][]string{"a": {"1", "2"}},
+			nodesPost: map[string][]string{"a": {"3", "4"}},
+			windows: []*fi.HazardWindow{
+				{Node: "a", Start: 1, End: 2, Length: 2, Closed: true},
+			},
+		},
+		{
+			name:      "global antecedent",
+			spec:      &fi.FailureSpec{EOT: 4, Nodes: &[]string{"a", "b"}},
+			timesPre:  []string{"2"},
+			nodesPost: map[string][]string{"a": {"3"}},
+			windows: []*fi.HazardWindow{
+				{Node: "a", Start: 2, End: 2, Length: 1, Closed: true},
+				{Node: "b", Start: 2, End: 2, Length: 1},
+			},
+		},
+		{
+			name:      "antecedent stops holding",
+			spec:      &fi.FailureSpec{EOT: 7, Nodes: &[]string{"a"}},
+			nodesPre:  map[string][]string{"a": {"1", "2", "4", "5", "7"}},
+			nodesPost: map[string][]string{"a": {"6", "7"}},
+			windows: []*fi.HazardWindow{
+				{Node: "a", Start: 1, End: 2, Length: 2},
+				{Node: "a", Start: 4, End: 5, Length: 2, Closed: true},
+			},
+		},
+		{
+			name: "correlated faults",
+			spec: &fi.FailureSpec{
+				EOT:   5,
+				Nodes: &[]string{"a", "b"},
+				Crashes: &[]fi.CrashFailure{
+					{Node: "a", Time: 2},
+					{Node: "b", Time: 1},
+				},
+				Omissions: &[]fi.MessageLoss{
+					{From: "b", To: "a", Time: 3},
+					{From: "a", To: "b", Time: 5},
+				},
+			},
+			nodesPre:  map[string][]string{"a": {"1", "2", "3"}},
+			nodesPost: map[string][]string{},
+			windows: []*fi.HazardWindow{
+				{
+					Node:      "a",
+					Start:     1,
+					End:       3,
+					Length:    3,
+					Crashes:   []fi.CrashFailure{{Node: "a", Time: 2}},
+					Omissions: []fi.MessageLoss{{From: "b", To: "a", Time: 3}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+
+		run := &fi.Run{
+			FailureSpec:    tt.spec,
+			NodesPostHolds: testHolds(tt.nodesPost),
+		}
+
+		if tt.nodesPre != nil {
+			run.NodesPreHolds = testHolds(tt.nodesPre)
+		} else {
+			run.TimePreHolds = testHolds(map[string][]string{"": tt.timesPre})[""]
+		}
+
+		windows := hazardIntervals(run, hazardWindow(run))
+		if !reflect.DeepEqual(windows, tt.windows) {
+
+			t.Errorf("%s: windows differ from expected ones", tt.name)
+			for _, w := range windows {
+				t.Logf("%s: found %+v", tt.name, *w)
+			}
+		}
+	}
+}
@@ -2,53 +2,149 @@ package graphing
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
-	"os/exec"
+	"net/url"
 
 	neo4j "github.com/johnnadratowski/golang-neo4j-bolt-driver"
 	fi "github.com/numbleroot/nemo/faultinjectors"
 )
 
+// Constants.
+
+const (
+	// defaultReadyTimeout bounds how long we wait for
+	// the graph database to accept connections.
+	defaultReadyTimeout = 60 * time.Second
+
+	// maxProbeBackoff caps the delay between two
+	// consecutive readiness probes.
+	maxProbeBackoff = 5 * time.Second
+)
+
 // Functions.
 
-// InitGraphDB
-func (n *Neo4J) InitGraphDB(boltURI string, runs []*fi.Run) error {
+// connString adds the configured credentials, if any,
+// to the supplied bolt connection URI.
+func (n *Neo4J) connString(boltURI string) (string, error) {
+
+	if (n.User == "") && (n.Password == "") {
+		return boltURI, nil
+	}
 
-	// Run the docker start command.
-	fmt.Printf("Starting docker containers... ")
-	cmd := exec.Command("sudo", "docker-compose", "-f", "docker-compose.yml", "up", "-d")
-	out, err := cmd.CombinedOutput()
+	u, err := url.Parse(boltURI)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("Invalid graph database URI '%s': %v", boltURI, err)
 	}
+	u.User = url.UserPassword(n.User, n.Password)
+
+	return u.String(), nil
+}
+
+// probe opens a connection and checks that
+// the graph database answers queries.
+func probe(driver neo4j.Driver, connStr string) (neo4j.Conn, error) {
 
-	if !strings.Contains(string(out), "done") {
-		return fmt.Errorf("Wrong return value from docker-compose up command: %s", out)
+	conn, err := driver.OpenNeo(connStr)
+	if err != nil {
+		return nil, err
 	}
-	fmt.Printf("done\n")
 
-	// Wait long enough for graph database to be up.
-	time.Sleep(10 * time.Second)
+	rows, err := conn.QueryNeo("RETURN 1;", nil)
+	if err == nil {
+		_, _, err = rows.All()
+		if err == nil {
+			err = rows.Close()
+		}
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// awaitReady repeatedly probes the graph database with
+// exponential backoff until it answers or the readiness
+// timeout expires.
+func (n *Neo4J) awaitReady(connStr string) error {
+
+	timeout := n.ReadyTimeout
+	if timeout <= 0 {
+		timeout = defaultReadyTimeout
+	}
 
 	driver := neo4j.NewDriver()
+	deadline := time.Now().Add(timeout)
+	backoff := 250 * time.Millisecond
+
+	for {
+
+		conn, err := probe(driver, connStr)
+		if err == nil {
+			return conn.Close()
+		}
 
-	// Connect to bolt endpoint.
-	c1, err := driver.OpenNeo(boltURI)
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("Graph database not ready after %v: %v", timeout, err)
+		}
+
+		time.Sleep(backoff)
+
+		backoff *= 2
+		if backoff > maxProbeBackoff {
+			backoff = maxProbeBackoff
+		}
+	}
+}
+
+// InitGraphDB
+func (n *Neo4J) InitGraphDB(boltURI string, analysis string, runs []*fi.Run) error {
+
+	// Start the containers, if we manage them.
+	if n.Containers != nil {
+
+		err := n.Containers.Start()
+		if err != nil {
+			return err
+		}
+	}
+
+	connStr, err := n.connString(boltURI)
 	if err != nil {
 		return err
 	}
 
-	c2, err := driver.OpenNeo(boltURI)
+	// Wait for graph database to be up.
+	fmt.Printf("Waiting for graph database... ")
+	err = n.awaitReady(connStr)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("done\n")
+
+	if n.Workers < 1 {
+		n.Workers = 1
+	}
+
+	// Open one connection per worker.
+	pool, err := neo4j.NewClosableDriverPool(connStr, n.Workers)
 	if err != nil {
 		return err
 	}
 
-	n.Conn1 = c1
-	n.Conn2 = c2
+	n.pool = pool
+	n.Analysis = analysis
 	n.Runs = runs
 
+	// Make sure constraints and indexes exist.
+	err = n.createSchema()
+	if err != nil {
+		return err
+	}
+
 	fmt.Println()
 
 	return nil
@@ -57,30 +153,21 @@ func (n *Neo4J) InitGraphDB(boltURI string, runs []*fi.Run) error {
 // CloseDB properly shuts down the Neo4J connection.
 func (n *Neo4J) CloseDB() error {
 
-	err := n.Conn1.Close()
+	err := n.pool.Close()
 	if err != nil {
 		return err
 	}
 
-	err = n.Conn2.Close()
-	if err != nil {
-		return err
-	}
-
-	time.Sleep(2 * time.Second)
+	// Shut down containers, if we manage them.
+	if n.Containers != nil {
 
-	// Shut down docker container.
-	fmt.Printf("Shutting down docker containers... ")
-	cmd := exec.Command("sudo", "docker-compose", "-f", "docker-compose.yml", "down")
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return err
-	}
+		time.Sleep(2 * time.Second)
 
-	if !strings.Contains(string(out), "done") {
-		return fmt.Errorf("Wrong return value from docker-compose down command: %s", out)
+		err = n.Containers.Stop()
+		if err != nil {
+			return err
+		}
 	}
-	fmt.Printf("done\n")
 
 	return nil
 }
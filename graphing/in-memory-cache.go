@@ -0,0 +1,244 @@
+package graphing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Structs.
+
+// memCacheEntry is one raw or simplified provenance
+// graph as stored in the in-memory cache file.
+type memCacheEntry struct {
+	Kind      GraphKind    `json:"kind"`
+	Run       uint         `json:"run"`
+	Condition string       `json:"condition"`
+	Prov      *fi.ProvData `json:"prov"`
+}
+
+// memCache is the content of one cache file.
+type memCache struct {
+	Analysis    string           `json:"analysis"`
+	Fingerprint string           `json:"fingerprint"`
+	Created     string           `json:"created"`
+	Runs        int64            `json:"runs"`
+	Graphs      []*memCacheEntry `json:"graphs"`
+}
+
+// memCacheHeader describes one cache file
+// without its provenance graphs.
+type memCacheHeader struct {
+	Analysis    string `json:"analysis"`
+	Fingerprint string `json:"fingerprint"`
+	Created     string `json:"created"`
+	Runs        int64  `json:"runs"`
+	file        string
+}
+
+// Functions.
+
+// cacheFile returns the path of the cache file
+// for inputs with the supplied fingerprint.
+func (m *InMemory) cacheFile(fingerprint string) string {
+	return filepath.Join(m.CacheDir, fmt.Sprintf("%s.json", fingerprint))
+}
+
+// readCacheHeader decodes the fields of cache file
+// preceding its provenance graphs, without reading them.
+func readCacheHeader(file string) (*memCacheHeader, error) {
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	if delim, ok := tok.(json.Delim); !ok || (delim != '{') {
+		return nil, fmt.Errorf("not a cache file")
+	}
+
+	header := &memCacheHeader{
+		file: file,
+	}
+
+	for dec.More() {
+
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok {
+
+		case "analysis":
+			err = dec.Decode(&header.Analysis)
+
+		case "fingerprint":
+			err = dec.Decode(&header.Fingerprint)
+
+		case "created":
+			err = dec.Decode(&header.Created)
+
+		case "runs":
+			err = dec.Decode(&header.Runs)
+
+		case "graphs":
+			// Graphs are written last.
+			return header, nil
+
+		default:
+			err = dec.Decode(&json.RawMessage{})
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return header, nil
+}
+
+// cachedAnalyses describes all cache files in the
+// cache directory. Files that are no cache files or
+// cannot be read are skipped with a warning.
+func (m *InMemory) cachedAnalyses() ([]*memCacheHeader, error) {
+
+	if m.CacheDir == "" {
+		return []*memCacheHeader{}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(m.CacheDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]*memCacheHeader, 0, len(files))
+	for _, file := range files {
+
+		header, err := readCacheHeader(file)
+		if (err == nil) && (header.Analysis == "") {
+			err = fmt.Errorf("no analysis recorded")
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping cache file '%s': %v\n", file, err)
+			continue
+		}
+
+		headers = append(headers, header)
+	}
+
+	return headers, nil
+}
+
+// RestoreCachedProv loads raw and simplified provenance
+// from the cache file for the supplied fingerprint, if
+// one exists. Without a cache directory, nothing is cached.
+func (m *InMemory) RestoreCachedProv(fingerprint string) (bool, error) {
+
+	if m.CacheDir == "" {
+		return false, nil
+	}
+
+	rawCache, err := ioutil.ReadFile(m.cacheFile(fingerprint))
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	cache := &memCache{}
+	err = json.Unmarshal(rawCache, cache)
+	if err != nil {
+		return false, fmt.Errorf("Failed to unmarshal cached provenance: %v", err)
+	}
+
+	if cache.Fingerprint != fingerprint {
+		return false, nil
+	}
+
+	fmt.Printf("Restoring cached provenance graphs... ")
+
+	m.graphs = make(map[memKey]*provGraph)
+	for _, entry := range cache.Graphs {
+		m.graphs[memKey{entry.Kind, entry.Run, entry.Condition}] = provGraphFromData(entry.Prov)
+	}
+	m.created = cache.Created
+
+	fmt.Printf("done\n\n")
+
+	return true, nil
+}
+
+// CacheProv writes raw and simplified provenance to
+// the cache file for the supplied fingerprint.
+func (m *InMemory) CacheProv(fingerprint string) error {
+
+	if m.CacheDir == "" {
+		return nil
+	}
+
+	cache := &memCache{
+		Analysis:    m.Analysis,
+		Fingerprint: fingerprint,
+		Created:     m.created,
+		Runs:        int64(len(m.Runs)),
+		Graphs:      make([]*memCacheEntry, 0, len(m.graphs)),
+	}
+
+	for key, g := range m.graphs {
+
+		if (key.kind != KindRaw) && (key.kind != KindClean) {
+			continue
+		}
+
+		cache.Graphs = append(cache.Graphs, &memCacheEntry{
+			Kind:      key.kind,
+			Run:       key.run,
+			Condition: key.condition,
+			Prov:      g.toProvData(),
+		})
+	}
+
+	// Keep the file stable across invocations.
+	sort.Slice(cache.Graphs, func(i, j int) bool {
+
+		a, b := cache.Graphs[i], cache.Graphs[j]
+
+		if a.Kind != b.Kind {
+			return a.Kind > b.Kind
+		}
+
+		if a.Run != b.Run {
+			return a.Run < b.Run
+		}
+
+		return a.Condition > b.Condition
+	})
+
+	rawCache, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(m.CacheDir, 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(m.cacheFile(fingerprint), rawCache, 0644)
+}
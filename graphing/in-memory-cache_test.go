@@ -0,0 +1,60 @@
+package graphing
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"path/filepath"
+)
+
+// Functions.
+
+func TestCachedAnalyses(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "nemo-cache")
+	if err != nil {
+		t.Fatalf("creating directory failed: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		// Graphs are never decoded, so a cut-off
+		// graph does not keep the header from listing.
+		"cut.json":     `{"analysis": "cut-123", "fingerprint": "123", "created": "2020-01-02", "runs": 3, "graphs": [{"kind": `,
+		"full.json":    `{"analysis": "full-456", "fingerprint": "456", "created": "2020-01-01", "runs": 2, "graphs": []}`,
+		"array.json":   `[1, 2]`,
+		"foreign.json": `{"name": "package.json"}`,
+		"broken.json":  `{"analysis": `,
+		"other.txt":    `{"analysis": "other-789"}`,
+	}
+
+	for name, content := range files {
+
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("writing %s failed: %v", name, err)
+		}
+	}
+
+	m := &InMemory{CacheDir: dir}
+
+	cached, err := m.cachedAnalyses()
+	if err != nil {
+		t.Fatalf("listing cache files failed: %v", err)
+	}
+
+	expected := []*memCacheHeader{
+		{Analysis: "cut-123", Fingerprint: "123", Created: "2020-01-02", Runs: 3, file: filepath.Join(dir, "cut.json")},
+		{Analysis: "full-456", Fingerprint: "456", Created: "2020-01-01", Runs: 2, file: filepath.Join(dir, "full.json")},
+	}
+
+	if !reflect.DeepEqual(cached, expected) {
+
+		t.Errorf("found %d cache files, expected %d", len(cached), len(expected))
+		for _, c := range cached {
+			t.Logf("found %+v", *c)
+		}
+	}
+}
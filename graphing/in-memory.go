@@ -0,0 +1,736 @@
+package graphing
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/awalterschulze/gographviz"
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Structs.
+
+// memKey identifies one provenance graph
+// held by the in-memory graph database.
+type memKey struct {
+	kind      GraphKind
+	run       uint
+	condition string
+}
+
+// InMemory is a pure-Go graph database that keeps
+// all provenance graphs in process. It requires
+// neither Neo4J nor Docker. If CacheDir is set,
+// raw and simplified provenance is cached there
+// across invocations.
+type InMemory struct {
+	Analysis string
+	CacheDir string
+	Runs     []*fi.Run
+	created  string
+	graphs   map[memKey]*provGraph
+}
+
+// memSink builds an in-process provenance graph
+// from streamed goals, rules, and edges, counting
+// received and inserted elements.
+type memSink struct {
+	g          *provGraph
+	deferred   []fi.Edge
+	goals      int64
+	goalsAdded int64
+	rules      int64
+	rulesAdded int64
+	edges      int64
+	edgesAdded int64
+}
+
+// Functions.
+
+// InitGraphDB prepares the in-memory graph database.
+// The connection URI is ignored.
+func (m *InMemory) InitGraphDB(boltURI string, analysis string, runs []*fi.Run) error {
+
+	m.Analysis = analysis
+	m.Runs = runs
+	m.graphs = make(map[memKey]*provGraph)
+
+	return nil
+}
+
+// CloseDB drops all held provenance graphs.
+func (m *InMemory) CloseDB() error {
+
+	m.graphs = nil
+
+	return nil
+}
+
+// ListAnalyses returns the analyses cached in the
+// cache directory and the one held in memory, if its
+// provenance has been loaded, oldest first.
+func (m *InMemory) ListAnalyses() ([]*Analysis, error) {
+
+	cached, err := m.cachedAnalyses()
+	if err != nil {
+		return nil, err
+	}
+
+	analyses := make([]*Analysis, 0, (len(cached) + 1))
+	listed := make(map[string]bool)
+
+	for _, c := range cached {
+
+		if listed[c.Analysis] {
+			continue
+		}
+		listed[c.Analysis] = true
+
+		analyses = append(analyses, &Analysis{
+			ID:      c.Analysis,
+			Created: c.Created,
+			Runs:    c.Runs,
+		})
+	}
+
+	if (m.created != "") && !listed[m.Analysis] {
+
+		analyses = append(analyses, &Analysis{
+			ID:      m.Analysis,
+			Created: m.created,
+			Runs:    int64(len(m.Runs)),
+		})
+	}
+
+	sort.Slice(analyses, func(i, j int) bool {
+
+		if analyses[i].Created != analyses[j].Created {
+			return analyses[i].Created < analyses[j].Created
+		}
+
+		return analyses[i].ID < analyses[j].ID
+	})
+
+	return analyses, nil
+}
+
+// DeleteAnalysis drops all provenance graphs if they
+// belong to the specified analysis and removes the
+// analysis' files from the cache directory.
+func (m *InMemory) DeleteAnalysis(analysis string) error {
+
+	found := false
+
+	if (analysis == m.Analysis) && (m.created != "") {
+		m.created = ""
+		m.graphs = make(map[memKey]*provGraph)
+		found = true
+	}
+
+	cached, err := m.cachedAnalyses()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cached {
+
+		if c.Analysis != analysis {
+			continue
+		}
+
+		err := os.Remove(c.file)
+		if err != nil {
+			return err
+		}
+		found = true
+	}
+
+	if !found {
+		return fmt.Errorf("No analysis '%s' found in '%s'", analysis, m.CacheDir)
+	}
+
+	return nil
+}
+
+// graph returns the provenance graph of specified kind,
+// run, and condition, or an empty one if none exists.
+func (m *InMemory) graph(kind GraphKind, run uint, condition string) *provGraph {
+
+	g, found := m.graphs[memKey{kind, run, condition}]
+	if !found {
+		return newProvGraph()
+	}
+
+	return g
+}
+
+// AddGoal
+func (s *memSink) AddGoal(goal *fi.Goal) error {
+
+	s.goals++
+	if s.g.addGoal(*goal) {
+		s.goalsAdded++
+	}
+
+	return nil
+}
+
+// AddRule
+func (s *memSink) AddRule(rule *fi.Rule) error {
+
+	s.rules++
+	if s.g.addRule(*rule) {
+		s.rulesAdded++
+	}
+
+	return nil
+}
+
+// AddEdge adds edge right away if both its endpoints
+// exist and defers it until the end otherwise.
+func (s *memSink) AddEdge(edge *fi.Edge) error {
+
+	s.edges++
+
+	if !s.g.has(edge.From) || !s.g.has(edge.To) {
+		s.deferred = append(s.deferred, *edge)
+		return nil
+	}
+
+	if s.g.addEdge(edge.From, edge.To) {
+		s.edgesAdded++
+	}
+
+	return nil
+}
+
+// loadProvFrom builds the provenance graph of specified
+// kind, run, and condition from the elements stream
+// passes to its sink.
+func (m *InMemory) loadProvFrom(kind GraphKind, iteration uint, provCond string, stream func(fi.ProvSink) error) error {
+
+	sink := &memSink{
+		g: newProvGraph(),
+	}
+
+	err := stream(sink)
+	if err != nil {
+		return err
+	}
+
+	for j := range sink.deferred {
+		if sink.g.addEdge(sink.deferred[j].From, sink.deferred[j].To) {
+			sink.edgesAdded++
+		}
+	}
+
+	// Verify number of inserted elements.
+	if sink.goals != sink.goalsAdded {
+		return fmt.Errorf("Run %d: inserted number of goals (%d) does not equal number of %s provenance goals (%d)", iteration, sink.goalsAdded, provCond, sink.goals)
+	}
+
+	if sink.rules != sink.rulesAdded {
+		return fmt.Errorf("Run %d: inserted number of rules (%d) does not equal number of %s provenance rules (%d)", iteration, sink.rulesAdded, provCond, sink.rules)
+	}
+
+	if sink.edges != sink.edgesAdded {
+		return fmt.Errorf("Run %d: inserted number of edges (%d) does not equal number of %s provenance edges (%d)", iteration, sink.edgesAdded, provCond, sink.edges)
+	}
+
+	m.graphs[memKey{kind, iteration, provCond}] = sink.g
+
+	return nil
+}
+
+// loadProv
+func (m *InMemory) loadProv(kind GraphKind, iteration uint, provCond string, provData *fi.ProvData) error {
+
+	return m.loadProvFrom(kind, iteration, provCond, func(sink fi.ProvSink) error {
+		return fi.StreamProvData(provData, sink)
+	})
+}
+
+// LoadRawProvenance builds the raw provenance graphs
+// of all runs from the elements source streams.
+func (m *InMemory) LoadRawProvenance(source fi.ProvStreamer) error {
+
+	fmt.Printf("Loading raw provenance data...\n")
+
+	m.created = time.Now().UTC().Format(time.RFC3339)
+
+	progress := newImportProgress(2 * len(m.Runs))
+	defer progress.finish()
+
+	for i := range m.Runs {
+
+		for _, cond := range []string{"pre", "post"} {
+
+			iter := m.Runs[i].Iteration
+
+			err := m.loadProvFrom(KindRaw, iter, cond, func(sink fi.ProvSink) error {
+				return source.StreamProv(iter, cond, progress.sink(sink))
+			})
+			if err != nil {
+				return err
+			}
+
+			// Taint goals for which the condition holds.
+			m.graph(KindRaw, iter, cond).markConditionHolds(cond)
+
+			progress.graphDone(iter, cond)
+		}
+	}
+
+	fmt.Println()
+
+	return nil
+}
+
+// SimplifyProv
+func (m *InMemory) SimplifyProv(iters []uint) error {
+
+	fmt.Printf("Preprocessing provenance graphs... ")
+
+	for i := range iters {
+
+		for _, condition := range []string{"pre", "post"} {
+
+			// Clean-copy provenance.
+			clean := m.graph(KindRaw, iters[i], condition).between(func(goal *fi.Goal) bool {
+				return true
+			}).renamed(idPrefix(KindRaw, iters[i], condition), idPrefix(KindClean, iters[i], condition))
+
+			// Collapse @next chains in copied provenance.
+			clean.collapseNextChains(idPrefix(KindClean, iters[i], condition))
+
+			m.graphs[memKey{KindClean, iters[i], condition}] = clean
+		}
+	}
+
+	fmt.Printf("done\n\n")
+
+	return nil
+}
+
+// CreateHazardAnalysis
+func (m *InMemory) CreateHazardAnalysis(faultInjOut fi.FS) ([]*gographviz.Graph, [][]*fi.HazardWindow, error) {
+	return createHazardAnalysis(m.Runs, faultInjOut)
+}
+
+// extractProtos extracts the intersection-prototype
+// and union-prototype from all iterations.
+func (m *InMemory) extractProtos(iters []uint, condition string) ([]string, []string) {
+
+	achvdCond := 0
+	iterProv := make([][]string, len(iters))
+
+	for i := range iters {
+
+		// Only consider rule labels in case the
+		// execution eventually achieved its antecedent.
+		if !m.graph(KindClean, iters[i], "pre").holds() {
+			continue
+		}
+
+		rules := m.graph(KindClean, iters[i], condition).protoRules()
+		if len(rules) > 0 {
+
+			// Count how many times the antecedent was achieved.
+			achvdCond += 1
+
+			// Add rules slice to tracking structure.
+			iterProv[i] = rules
+		}
+	}
+
+	return buildProtos(iterProv, achvdCond, condition)
+}
+
+// CreatePrototypes
+func (m *InMemory) CreatePrototypes(iters []uint, failedIters []uint) ([]string, [][]string, []string, [][]string, error) {
+
+	fmt.Printf("Running extraction of success prototypes... ")
+
+	// Create consequent intersection-prototype
+	// and union-prototype.
+	interProto, unionProto := m.extractProtos(iters, "post")
+
+	interProtoMiss := make([][]string, len(failedIters))
+	unionProtoMiss := make([][]string, len(failedIters))
+
+	for i := range failedIters {
+
+		failedRules := m.graph(KindClean, failedIters[i], "post").ruleTables()
+
+		// Collect all nodes missing in the failed execution's consequent
+		// provenance that are part of the intersection-prototype.
+		interProtoMiss[i] = missingRules(interProto, failedRules)
+
+		// Collect all nodes missing in the failed execution's consequent
+		// provenance that are part of the union-prototype.
+		unionProtoMiss[i] = missingRules(unionProto, failedRules)
+	}
+
+	for i := range interProto {
+		interProto[i] = fmt.Sprintf("<code>%s</code>", interProto[i])
+	}
+
+	for i := range unionProto {
+		unionProto[i] = fmt.Sprintf("<code>%s</code>", unionProto[i])
+	}
+
+	fmt.Printf("done\n\n")
+
+	return interProto, interProtoMiss, unionProto, unionProtoMiss, nil
+}
+
+// PullPrePostProv
+func (m *InMemory) PullPrePostProv() ([]*gographviz.Graph, []*gographviz.Graph, []*gographviz.Graph, []*gographviz.Graph, error) {
+
+	fmt.Printf("Pulling antecedent and consequent provenance... ")
+
+	preDots := make([]*gographviz.Graph, len(m.Runs))
+	postDots := make([]*gographviz.Graph, len(m.Runs))
+	preCleanDots := make([]*gographviz.Graph, len(m.Runs))
+	postCleanDots := make([]*gographviz.Graph, len(m.Runs))
+
+	for i := range m.Runs {
+
+		preDot, err := createDOT(m.graph(KindRaw, m.Runs[i].Iteration, "pre").paths(), "pre")
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		postDot, err := createDOT(m.graph(KindRaw, m.Runs[i].Iteration, "post").paths(), "post")
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		preCleanDot, err := createDOT(m.graph(KindClean, m.Runs[i].Iteration, "pre").paths(), "pre")
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		postCleanDot, err := createDOT(m.graph(KindClean, m.Runs[i].Iteration, "post").paths(), "post")
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		preDots[i] = preDot
+		postDots[i] = postDot
+		preCleanDots[i] = preCleanDot
+		postCleanDots[i] = postCleanDot
+	}
+
+	fmt.Printf("done\n\n")
+
+	return preDots, postDots, preCleanDots, postCleanDots, nil
+}
+
+// CreateNaiveDiffProv
+func (m *InMemory) CreateNaiveDiffProv(symmetric bool, failedRuns []uint, successRuns []uint, postProvDots []*gographviz.Graph) ([]*gographviz.Graph, []*gographviz.Graph, [][]*fi.Missing, []*gographviz.Graph, [][]*fi.Missing, error) {
+
+	fmt.Printf("Creating differential provenance (%s), naive way... ", diffTitle(symmetric))
+
+	diffDots := make([]*gographviz.Graph, len(failedRuns))
+	failedDots := make([]*gographviz.Graph, len(failedRuns))
+	missingEvents := make([][]*fi.Missing, len(failedRuns))
+
+	var excessDots []*gographviz.Graph
+	var excessEvents [][]*fi.Missing
+	if symmetric {
+		excessDots = make([]*gographviz.Graph, len(failedRuns))
+		excessEvents = make([][]*fi.Missing, len(failedRuns))
+	}
+
+	for i := range failedRuns {
+
+		success := m.graph(KindRaw, successRuns[i], "post")
+		failed := m.graph(KindRaw, failedRuns[i], "post")
+
+		// Align both runs' provenance modulo time shifts.
+		alignment := alignProv(success, failed)
+
+		// Keep all paths of the successful run whose start
+		// and end goals have no counterpart in the failed run.
+		diff := success.between(func(goal *fi.Goal) bool {
+			return alignment.missing(goal.ID)
+		}).renamed(idPrefix(KindRaw, successRuns[i], "post"), idPrefix(KindDiff, failedRuns[i], "post"))
+
+		m.graphs[memKey{KindDiff, failedRuns[i], "post"}] = diff
+
+		// Determine the deepest rules in the
+		// differential provenance and their leaves.
+		missing := diff.missingLeaves()
+
+		// Pass to DOT string generator.
+		diffDot, failedDot, err := createDiffDot(diff.paths(), failedRuns[i], failed.paths(), successRuns[i], postProvDots[successRuns[i]], missing, alignment.renamed(idPrefix(KindRaw, successRuns[i], "post"), idPrefix(KindDiff, failedRuns[i], "post")))
+		if err != nil {
+			return nil, nil, nil, nil, nil, err
+		}
+
+		diffDots[i] = diffDot
+		failedDots[i] = failedDot
+		missingEvents[i] = missing
+
+		if symmetric {
+
+			// Keep all paths of the failed run whose start and
+			// end goals have no counterpart in the successful one.
+			excessProv := failedOnly(alignment, failed, failedRuns[i])
+
+			m.graphs[memKey{KindExcess, failedRuns[i], "post"}] = excessProv
+
+			excess := excessProv.missingLeaves()
+
+			excessDot, err := createExcessDot(excessProv.paths(), failedRuns[i], postProvDots[failedRuns[i]], excess)
+			if err != nil {
+				return nil, nil, nil, nil, nil, err
+			}
+
+			excessDots[i] = excessDot
+			excessEvents[i] = excess
+		}
+	}
+
+	fmt.Printf("done\n\n")
+
+	return diffDots, failedDots, missingEvents, excessDots, excessEvents, nil
+}
+
+// PairRuns
+func (m *InMemory) PairRuns(successRuns []uint, failedRuns []uint) ([]*fi.RunPairing, error) {
+
+	return pairRuns(successRuns, failedRuns, func(run uint) (*provGraph, error) {
+		return m.graph(KindRaw, run, "post"), nil
+	})
+}
+
+// AlignProv
+func (m *InMemory) AlignProv(failedRuns []uint, successRuns []uint) ([]*fi.ProvAlignment, error) {
+
+	return alignRuns(failedRuns, successRuns, func(run uint) (*provGraph, error) {
+		return m.graph(KindRaw, run, "post"), nil
+	})
+}
+
+// RankRootCauses
+func (m *InMemory) RankRootCauses(failedRuns []uint, missing [][]*fi.Missing, k int) ([][]*fi.RootCause, error) {
+
+	return rankRootCauses(m.Runs, failedRuns, missing, k, func(run uint) (*provGraph, error) {
+		return m.graph(KindDiff, run, "post"), nil
+	})
+}
+
+// LocalizeFaults
+func (m *InMemory) LocalizeFaults(successRuns []uint, failedRuns []uint) ([]*fi.Suspiciousness, error) {
+
+	return localizeFaults(successRuns, failedRuns, func(run uint, condition string) (*provGraph, error) {
+		return m.graph(KindRaw, run, condition), nil
+	})
+}
+
+// CreateMessageDiff
+func (m *InMemory) CreateMessageDiff(successRuns []uint, failedRuns []uint, failedMsgs [][]*fi.Message) ([][]*fi.MessageDiff, error) {
+
+	return createMessageDiffs(m.Runs, successRuns, failedRuns, failedMsgs, func(run uint) (*provGraph, error) {
+		return m.graph(KindRaw, run, "post"), nil
+	})
+}
+
+// findPreTriggers extracts the trigger events
+// that mark the transition from the antecedent
+// turning from false to true.
+func (m *InMemory) findPreTriggers(run uint) map[*fi.Rule][]*GoalRulePair {
+
+	g := m.graph(KindRaw, run, "pre")
+
+	// Prepare a map indexed by aggregation rule,
+	// collecting all trigger goals and rules.
+	triggers := make(map[*fi.Rule][]*GoalRulePair)
+
+	for _, aggID := range g.order {
+
+		if !g.isRule(aggID) {
+			continue
+		}
+
+		// The aggregation rule needs to be derived
+		// from a goal for which the antecedent holds.
+		aggHolds := false
+		for _, pred := range g.preds[aggID] {
+
+			if g.goals[pred].CondHolds {
+				aggHolds = true
+			}
+		}
+
+		if !aggHolds {
+			continue
+		}
+
+		for _, goalID := range g.succs[aggID] {
+
+			if g.goals[goalID].CondHolds {
+				continue
+			}
+
+			for _, ruleID := range g.succs[goalID] {
+
+				aggregation := *g.rules[aggID]
+				goal := *g.goals[goalID]
+				rule := *g.rules[ruleID]
+
+				goal.Receiver = goalReceiver(goal.Label, goal.Table)
+
+				// Insert goal-rule pair into slice indexed
+				// by aggregation rule.
+				triggers[&aggregation] = append(triggers[&aggregation], &GoalRulePair{
+					Goal: &goal,
+					Rule: &rule,
+				})
+			}
+		}
+	}
+
+	return triggers
+}
+
+// findPostTriggers extracts the trigger events
+// that mark the transition from the consequent
+// turning from false to true.
+func (m *InMemory) findPostTriggers(run uint) map[*fi.Goal][]*fi.Rule {
+
+	g := m.graph(KindRaw, run, "post")
+
+	// Prepare a map indexed by trigger goal,
+	// collecting all trigger rules.
+	triggers := make(map[*fi.Goal][]*fi.Rule)
+
+	for _, goalID := range g.order {
+
+		if !g.isGoal(goalID) || !g.goals[goalID].CondHolds || (len(g.preds[goalID]) == 0) {
+			continue
+		}
+
+		for _, ruleID := range g.succs[goalID] {
+
+			// The trigger rule needs to lead to a goal
+			// for which the consequent does not hold yet.
+			leadsToUnheld := false
+			for _, succ := range g.succs[ruleID] {
+
+				if !g.goals[succ].CondHolds && (len(g.succs[succ]) > 0) {
+					leadsToUnheld = true
+				}
+			}
+
+			if !leadsToUnheld {
+				continue
+			}
+
+			goal := *g.goals[goalID]
+			rule := *g.rules[ruleID]
+
+			goal.Receiver = goalReceiver(goal.Label, goal.Table)
+
+			// Insert rule into slice indexed by goal.
+			triggers[&goal] = append(triggers[&goal], &rule)
+		}
+	}
+
+	return triggers
+}
+
+// GenerateCorrections extracts the triggering events required
+// to achieve antecedent and consequent in successful run
+// successRun and turns them into correction suggestions.
+func (m *InMemory) GenerateCorrections(successRun uint) ([]string, error) {
+
+	fmt.Printf("Running generation of suggestions for corrections (pre ~> post)... ")
+
+	recs := correctionsFromTriggers(m.findPreTriggers(successRun), m.findPostTriggers(successRun))
+
+	fmt.Printf("done\n\n")
+
+	return recs, nil
+}
+
+// GenerateExtensions
+func (m *InMemory) GenerateExtensions(successRun uint) (bool, []string, error) {
+
+	// Prepare slice of extensions.
+	extensions := make([]string, 0, 3)
+
+	// Prepare map for adding extensions only once per rule.
+	rulesState := make(map[string]string)
+
+	// Count antecedent achievements over all runs.
+	preAchieved := 0
+	for i := range m.Runs {
+
+		for _, goal := range m.graph(KindRaw, m.Runs[i].Iteration, "pre").goals {
+
+			if (goal.Table == "pre") && goal.CondHolds {
+				preAchieved++
+			}
+		}
+	}
+
+	// Only in case we have as many achievements as
+	// our execution has runs, all runs achieved pre.
+	allAchievedPre := preAchieved >= len(m.Runs)
+
+	if !allAchievedPre {
+
+		// In case not all runs achieved the antecedent,
+		// we query the successful run and collect all
+		// network events.
+
+		g := m.graph(KindRaw, successRun, "pre")
+
+		for _, ruleID := range g.order {
+
+			rule, isRule := g.rules[ruleID]
+			if !isRule || (rule.Type != "async") {
+				continue
+			}
+
+			fromHeld := false
+			fromUnheld := false
+			for _, pred := range g.preds[ruleID] {
+
+				if g.goals[pred].CondHolds {
+					fromHeld = true
+				} else {
+					fromUnheld = true
+				}
+			}
+
+			toUnheld := false
+			for _, succ := range g.succs[ruleID] {
+
+				if !g.goals[succ].CondHolds && (len(g.succs[succ]) > 0) {
+					toUnheld = true
+				}
+			}
+
+			if (fromHeld && toUnheld) || fromUnheld {
+
+				// Add rule to extension suggestions only
+				// in case we did not already do so.
+				rulesState[rule.Table] = fmt.Sprintf("<code>%s(node, ...)@async :- ...;</code>", rule.Table)
+			}
+		}
+
+		for rule := range rulesState {
+
+			// Append an extension suggestion to the final slice.
+			extensions = append(extensions, rulesState[rule])
+		}
+	}
+
+	return allAchievedPre, extensions, nil
+}
@@ -0,0 +1,116 @@
+package graphing
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Functions.
+
+// labels returns the sorted labels of all goals of
+// table in g, or of all rules of table if rules is set.
+func labels(g *provGraph, table string, rules bool) []string {
+
+	found := make([]string, 0, 4)
+
+	for _, id := range g.order {
+
+		if !rules && g.isGoal(id) && (g.goals[id].Table == table) {
+			found = append(found, g.goals[id].Label)
+		}
+
+		if rules && g.isRule(id) && (g.rules[id].Table == table) {
+			found = append(found, (g.rules[id].Label + "@" + g.rules[id].Type))
+		}
+	}
+	sort.Strings(found)
+
+	return found
+}
+
+func TestInMemoryMolly(t *testing.T) {
+
+	// Run 0 broadcasts from a to b and c, run 1
+	// loses the broadcast to b.
+	molly := &fi.Molly{
+		Run:       "molly-bcast",
+		OutputDir: "testdata/molly-bcast",
+	}
+
+	err := molly.LoadOutput()
+	if err != nil {
+		t.Fatalf("loading Molly output failed: %v", err)
+	}
+
+	m := &InMemory{}
+
+	err = m.InitGraphDB("", "molly-bcast", molly.GetOutput())
+	if err != nil {
+		t.Fatalf("initializing failed: %v", err)
+	}
+
+	err = m.LoadRawProvenance(molly)
+	if err != nil {
+		t.Fatalf("loading provenance failed: %v", err)
+	}
+
+	// The consequent and the broadcast log it is
+	// derived from directly hold.
+	raw := m.graph(KindRaw, 0, "post")
+	for _, goal := range raw.goals {
+
+		holds := (goal.Table == "post") || (goal.Table == "log")
+		if goal.CondHolds != holds {
+			t.Errorf("raw: condition holds at %s is %t, expected %t", goal.Label, goal.CondHolds, holds)
+		}
+	}
+
+	err = m.SimplifyProv([]uint{0, 1})
+	if err != nil {
+		t.Fatalf("simplifying provenance failed: %v", err)
+	}
+
+	// Both @next chains of logs are collapsed
+	// into one rule each.
+	clean := m.graph(KindClean, 0, "post")
+	if rules := labels(clean, "log", true); !reflect.DeepEqual(rules, []string{"log@async", "log@async", "log_collapsed@collapsed", "log_collapsed@collapsed"}) {
+		t.Errorf("clean: log rules are %v", rules)
+	}
+
+	if goals := labels(clean, "log", false); !reflect.DeepEqual(goals, []string{"log(a, data, 1)", "log(a, data, 1)", "log(b, data, 2)", "log(b, data, 4)", "log(c, data, 2)", "log(c, data, 4)"}) {
+		t.Errorf("clean: log goals are %v", goals)
+	}
+
+	_, postProvDots, _, _, err := m.PullPrePostProv()
+	if err != nil {
+		t.Fatalf("pulling provenance failed: %v", err)
+	}
+
+	_, _, missing, _, _, err := m.CreateNaiveDiffProv(false, []uint{1}, []uint{0}, postProvDots)
+	if err != nil {
+		t.Fatalf("creating differential provenance failed: %v", err)
+	}
+
+	// Only the consequent at b is missing in run 1.
+	diff := m.graph(KindDiff, 1, "post")
+	if goals := labels(diff, "post", false); !reflect.DeepEqual(goals, []string{"post(b, data, 4)"}) {
+		t.Errorf("diff: consequent goals are %v, expected [post(b, data, 4)]", goals)
+	}
+
+	if goals := labels(diff, "log", false); !reflect.DeepEqual(goals, []string{"log(b, data, 2)", "log(b, data, 3)", "log(b, data, 4)"}) {
+		t.Errorf("diff: log goals are %v", goals)
+	}
+
+	// The deepest missing event is the broadcast to b.
+	if len(missing[0]) != 1 {
+		t.Fatalf("found %d missing events, expected 1", len(missing[0]))
+	}
+
+	event := missing[0][0]
+	if (event.Rule.Table != "log") || (event.Rule.Type != "async") || (len(event.Goals) != 2) {
+		t.Errorf("missing event is %s@%s with %d goals, expected log@async with 2 goals", event.Rule.Table, event.Rule.Type, len(event.Goals))
+	}
+}
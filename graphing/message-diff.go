@@ -0,0 +1,285 @@
+package graphing
+
+import (
+	"fmt"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Structs.
+
+// msgKey aligns messages across runs by
+// table, sender, receiver, and send time.
+type msgKey struct {
+	table    string
+	from     string
+	to       string
+	sendTime uint
+}
+
+// msgHop is a message as it appears in provenance:
+// a goal at the receiver derived by an async rule
+// from goals at the sender.
+type msgHop struct {
+	from     string
+	to       string
+	sendTime string
+	recvTime string
+}
+
+// Functions.
+
+// keyOf returns the alignment key of msg.
+func keyOf(msg *fi.Message) msgKey {
+
+	return msgKey{
+		table:    msg.Content,
+		from:     msg.SendNode,
+		to:       msg.RecvNode,
+		sendTime: msg.SendTime,
+	}
+}
+
+// hopOf returns how msg appears in provenance.
+func hopOf(msg *fi.Message) msgHop {
+
+	return msgHop{
+		from:     msg.SendNode,
+		to:       msg.RecvNode,
+		sendTime: fmt.Sprintf("%d", msg.SendTime),
+		recvTime: fmt.Sprintf("%d", msg.RecvTime),
+	}
+}
+
+// msgHops collects all message receptions in the
+// provenance graph: for every async rule, the hops
+// from the location and time of its body goals to
+// the location and time of its head goal.
+func (g *provGraph) msgHops() map[msgHop]bool {
+
+	hops := make(map[msgHop]bool)
+
+	for ruleID, rule := range g.rules {
+
+		if rule.Type != "async" {
+			continue
+		}
+
+		for _, headID := range g.preds[ruleID] {
+
+			head := g.goals[headID]
+			if head == nil {
+				continue
+			}
+
+			for _, bodyID := range g.succs[ruleID] {
+
+				body := g.goals[bodyID]
+				if body == nil {
+					continue
+				}
+
+				hops[msgHop{
+					from:     goalReceiver(body.Label, body.Table),
+					to:       goalReceiver(head.Label, head.Table),
+					sendTime: body.Time,
+					recvTime: head.Time,
+				}] = true
+			}
+		}
+	}
+
+	return hops
+}
+
+// msgFault returns the omission or crash in spec that
+// explains why msg was not delivered, if any.
+func msgFault(spec *fi.FailureSpec, msg *fi.Message) (*fi.MessageLoss, *fi.CrashFailure) {
+
+	if spec == nil {
+		return nil, nil
+	}
+
+	if spec.Omissions != nil {
+		for i := range *spec.Omissions {
+
+			omission := (*spec.Omissions)[i]
+			if (omission.From == msg.SendNode) && (omission.To == msg.RecvNode) && (omission.Time == msg.SendTime) {
+				return &omission, nil
+			}
+		}
+	}
+
+	if spec.Crashes != nil {
+		for i := range *spec.Crashes {
+
+			crash := (*spec.Crashes)[i]
+			if ((crash.Node == msg.SendNode) && (crash.Time <= msg.SendTime)) ||
+				((crash.Node == msg.RecvNode) && (crash.Time <= msg.RecvTime)) {
+				return nil, &crash
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// messageDiff aligns the messages of successful run good
+// with failedMsgs, the messages of failed run failed. It
+// reports each message of the successful run that the
+// failed run dropped or delivered late. Messages among
+// necessary, the ones the successful run's consequent
+// depends on, are marked as such.
+func messageDiff(good *fi.Run, necessary map[msgHop]bool, failed *fi.Run, failedMsgs []*fi.Message) []*fi.MessageDiff {
+
+	// Index messages of the failed run for alignment.
+	// Missing entries count as matched to skip them.
+	byKey := make(map[msgKey][]int)
+	matched := make([]bool, len(failedMsgs))
+	for i := range failedMsgs {
+
+		if failedMsgs[i] == nil {
+			matched[i] = true
+			continue
+		}
+
+		key := keyOf(failedMsgs[i])
+		byKey[key] = append(byKey[key], i)
+	}
+
+	diffs := make([]*fi.MessageDiff, 0, 5)
+	unmatched := make([]*fi.Message, 0, 5)
+
+	// First, align messages sent at the same time.
+	for _, msg := range good.Messages {
+
+		if msg == nil {
+			continue
+		}
+
+		var aligned *fi.Message
+		for _, j := range byKey[keyOf(msg)] {
+
+			if !matched[j] {
+				matched[j] = true
+				aligned = failedMsgs[j]
+				break
+			}
+		}
+
+		if aligned == nil {
+			unmatched = append(unmatched, msg)
+			continue
+		}
+
+		omission, crash := msgFault(failed.FailureSpec, msg)
+
+		if (omission != nil) || (crash != nil) {
+
+			// Listed, but lost to a fault.
+			diffs = append(diffs, &fi.MessageDiff{
+				Message:   msg,
+				Status:    "dropped",
+				Omission:  omission,
+				Crash:     crash,
+				Necessary: necessary[hopOf(msg)],
+			})
+		} else if aligned.RecvTime > msg.RecvTime {
+
+			diffs = append(diffs, &fi.MessageDiff{
+				Message:   msg,
+				Status:    "delayed",
+				Delay:     (aligned.RecvTime - msg.RecvTime),
+				Necessary: necessary[hopOf(msg)],
+			})
+		}
+	}
+
+	// Then, consider messages with the same table, sender,
+	// and receiver, sent later in the failed run, as delayed.
+	// All others were dropped.
+	for _, msg := range unmatched {
+
+		later := -1
+		for j := range failedMsgs {
+
+			if matched[j] || (failedMsgs[j].Content != msg.Content) ||
+				(failedMsgs[j].SendNode != msg.SendNode) || (failedMsgs[j].RecvNode != msg.RecvNode) ||
+				(failedMsgs[j].SendTime <= msg.SendTime) || (failedMsgs[j].RecvTime <= msg.RecvTime) {
+				continue
+			}
+
+			if (later == -1) || (failedMsgs[j].SendTime < failedMsgs[later].SendTime) {
+				later = j
+			}
+		}
+
+		omission, crash := msgFault(failed.FailureSpec, msg)
+
+		diff := &fi.MessageDiff{
+			Message:   msg,
+			Status:    "dropped",
+			Omission:  omission,
+			Crash:     crash,
+			Necessary: necessary[hopOf(msg)],
+		}
+
+		if later != -1 {
+			matched[later] = true
+			diff.Status = "delayed"
+			diff.Delay = failedMsgs[later].RecvTime - msg.RecvTime
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	return diffs
+}
+
+// createMessageDiffs runs the message-level differential
+// analysis of each failed run against its successful run
+// in successRuns, pulling provenance graphs via prov.
+func createMessageDiffs(runs []*fi.Run, successRuns []uint, failedRuns []uint, failedMsgs [][]*fi.Message, prov func(run uint) (*provGraph, error)) ([][]*fi.MessageDiff, error) {
+
+	fmt.Printf("Comparing message flows of failed and successful runs... ")
+
+	// Collect each successful run's message hops once.
+	necessary := make(map[uint]map[msgHop]bool)
+
+	msgDiffs := make([][]*fi.MessageDiff, len(failedRuns))
+
+	for i := range failedRuns {
+
+		hops, collected := necessary[successRuns[i]]
+		if !collected {
+
+			goodProv, err := prov(successRuns[i])
+			if err != nil {
+				return nil, err
+			}
+
+			hops = goodProv.msgHops()
+			necessary[successRuns[i]] = hops
+		}
+
+		msgDiffs[i] = messageDiff(runs[successRuns[i]], hops, runs[failedRuns[i]], failedMsgs[i])
+	}
+
+	fmt.Printf("done\n\n")
+
+	return msgDiffs, nil
+}
+
+// CreateMessageDiff
+func (n *Neo4J) CreateMessageDiff(successRuns []uint, failedRuns []uint, failedMsgs [][]*fi.Message) ([][]*fi.MessageDiff, error) {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return createMessageDiffs(n.Runs, successRuns, failedRuns, failedMsgs, func(run uint) (*provGraph, error) {
+		return n.pullProvGraph(conn, KindRaw, run, "post")
+	})
+}
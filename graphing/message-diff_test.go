@@ -0,0 +1,107 @@
+package graphing
+
+import (
+	"reflect"
+	"testing"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Functions.
+
+// testMsg builds a message of table from sender
+// to receiver, sent and received at the given times.
+func testMsg(table string, from string, to string, sendTime uint, recvTime uint) *fi.Message {
+
+	return &fi.Message{
+		Content:  table,
+		SendNode: from,
+		RecvNode: to,
+		SendTime: sendTime,
+		RecvTime: recvTime,
+	}
+}
+
+func TestMessageDiff(t *testing.T) {
+
+	toB := testMsg("bcast", "a", "b", 1, 2)
+	toC := testMsg("bcast", "a", "c", 1, 2)
+
+	tests := []struct {
+		name      string
+		spec      *fi.FailureSpec
+		failedMsg []*fi.Message
+		diffs     []*fi.MessageDiff
+	}{
+		{
+			name:      "identical",
+			spec:      &fi.FailureSpec{},
+			failedMsg: []*fi.Message{testMsg("bcast", "a", "b", 1, 2), testMsg("bcast", "a", "c", 1, 2)},
+			diffs:     []*fi.MessageDiff{},
+		},
+		{
+			name:      "lost to omission",
+			spec:      &fi.FailureSpec{Omissions: &[]fi.MessageLoss{{From: "a", To: "b", Time: 1}}},
+			failedMsg: []*fi.Message{testMsg("bcast", "a", "c", 1, 2)},
+			diffs: []*fi.MessageDiff{
+				{Message: toB, Status: "dropped", Omission: &fi.MessageLoss{From: "a", To: "b", Time: 1}, Necessary: true},
+			},
+		},
+		{
+			name:      "listed but receiver crashed",
+			spec:      &fi.FailureSpec{Crashes: &[]fi.CrashFailure{{Node: "c", Time: 2}}},
+			failedMsg: []*fi.Message{testMsg("bcast", "a", "b", 1, 2), testMsg("bcast", "a", "c", 1, 2)},
+			diffs: []*fi.MessageDiff{
+				{Message: toC, Status: "dropped", Crash: &fi.CrashFailure{Node: "c", Time: 2}},
+			},
+		},
+		{
+			name:      "received later",
+			spec:      &fi.FailureSpec{},
+			failedMsg: []*fi.Message{testMsg("bcast", "a", "b", 1, 4), testMsg("bcast", "a", "c", 1, 2)},
+			diffs: []*fi.MessageDiff{
+				{Message: toB, Status: "delayed", Delay: 2, Necessary: true},
+			},
+		},
+		{
+			name:      "sent later",
+			spec:      &fi.FailureSpec{},
+			failedMsg: []*fi.Message{testMsg("bcast", "a", "b", 1, 2), testMsg("bcast", "a", "c", 2, 3)},
+			diffs: []*fi.MessageDiff{
+				{Message: toC, Status: "delayed", Delay: 1},
+			},
+		},
+		{
+			name:      "null messages of failed run",
+			spec:      &fi.FailureSpec{},
+			failedMsg: []*fi.Message{nil, testMsg("bcast", "a", "c", 1, 2), nil},
+			diffs: []*fi.MessageDiff{
+				{Message: toB, Status: "dropped", Necessary: true},
+			},
+		},
+	}
+
+	good := &fi.Run{
+		Messages: []*fi.Message{toB, nil, toC},
+	}
+
+	// Only the message to b leads to the consequent.
+	necessary := map[msgHop]bool{hopOf(toB): true}
+
+	for _, tt := range tests {
+
+		failed := &fi.Run{
+			FailureSpec: tt.spec,
+			Messages:    tt.failedMsg,
+		}
+
+		diffs := messageDiff(good, necessary, failed, tt.failedMsg)
+		if !reflect.DeepEqual(diffs, tt.diffs) {
+
+			t.Errorf("%s: message diff differs from expected one", tt.name)
+			for _, d := range diffs {
+				t.Logf("%s: found %+v", tt.name, *d)
+			}
+		}
+	}
+}
@@ -2,7 +2,7 @@ package graphing
 
 import (
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/awalterschulze/gographviz"
 	neo4j "github.com/johnnadratowski/golang-neo4j-bolt-driver"
@@ -10,224 +10,409 @@ import (
 	fi "github.com/numbleroot/nemo/faultinjectors"
 )
 
+// Constants.
+
+// importBatchSize is the maximum number of goals, rules,
+// or edges imported into Neo4J with one statement.
+const importBatchSize = 1000
+
 // Structs.
 
 // Neo4J
 type Neo4J struct {
-	Conn1 neo4j.Conn
-	Conn2 neo4j.Conn
-	Runs  []*fi.Run
+	User         string
+	Password     string
+	Containers   ContainerManager
+	ReadyTimeout time.Duration
+	Workers      int
+	pool         neo4j.ClosableDriverPool
+	Analysis     string
+	Runs         []*fi.Run
+}
+
+// neoSink imports streamed goals, rules, and edges
+// into Neo4J, importBatchSize elements at a time.
+// Besides the current batch, it only keeps the IDs
+// seen so far in memory, to sort edges by type.
+type neoSink struct {
+	n             *Neo4J
+	conn          neo4j.Conn
+	iteration     uint
+	cond          string
+	params        map[string]interface{}
+	isGoal        map[string]bool
+	isRule        map[string]bool
+	goals         []interface{}
+	rules         []interface{}
+	goalRuleEdges []interface{}
+	ruleGoalEdges []interface{}
+	deferred      []fi.Edge
+	numGoals      int64
+	numRules      int64
+	numEdges      int64
+	goalsCreated  int64
+	rulesCreated  int64
+	edgesCreated  int64
 }
 
 // Functions.
 
-// loadProv
-func (n *Neo4J) loadProv(iteration uint, provCond string, provData *fi.ProvData) error {
-
-	stmtGoal, err := n.Conn1.PrepareNeo(`
-		CREATE (goal:Goal {id: {id}, run: {run}, condition: {condition}, label: {label}, table: {table}, time: {time}, condition_holds: {condition_holds}});
-	`)
-	if err != nil {
-		return err
-	}
+// execBatched runs query once per chunk of at most
+// importBatchSize rows, passing the chunk as parameter
+// batch, and returns the summed-up statistic stat.
+func (n *Neo4J) execBatched(conn neo4j.Conn, query string, rows []interface{}, params map[string]interface{}, stat string) (int64, error) {
 
 	var resCnt int64 = 0
 
-	for j := range provData.Goals {
-
-		// Create a goal node.
-		res, err := stmtGoal.ExecNeo(map[string]interface{}{
-			"id":              provData.Goals[j].ID,
-			"run":             iteration,
-			"condition":       provCond,
-			"label":           provData.Goals[j].Label,
-			"table":           provData.Goals[j].Table,
-			"time":            provData.Goals[j].Time,
-			"condition_holds": provData.Goals[j].CondHolds,
-		})
-		if err != nil {
-			return err
+	for start := 0; start < len(rows); start += importBatchSize {
+
+		end := start + importBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		batchParams := map[string]interface{}{
+			"batch": rows[start:end],
+		}
+		for k, v := range params {
+			batchParams[k] = v
 		}
 
-		// Collect affected rows information.
-		rowsAff, err := res.RowsAffected()
+		res, err := conn.ExecNeo(query, batchParams)
 		if err != nil {
-			return err
+			return resCnt, err
+		}
+
+		// Track number of created elements.
+		stats, ok := res.Metadata()["stats"].(map[string]interface{})
+		if ok {
+			if created, ok := stats[stat].(int64); ok {
+				resCnt += created
+			}
 		}
-		resCnt += rowsAff
 	}
 
-	err = stmtGoal.Close()
+	return resCnt, nil
+}
+
+// loadProv imports the supplied provenance graph in
+// batches within one transaction. If any step fails,
+// the transaction is rolled back so that no partial
+// graph is left behind.
+func (n *Neo4J) loadProv(conn neo4j.Conn, kind GraphKind, iteration uint, provCond string, provData *fi.ProvData) error {
+
+	return n.loadProvFrom(conn, kind, iteration, provCond, func(sink fi.ProvSink) error {
+		return fi.StreamProvData(provData, sink)
+	})
+}
+
+// loadProvFrom imports the provenance graph whose
+// elements stream passes to its sink, in batches
+// within one transaction. If any step fails, the
+// transaction is rolled back so that no partial
+// graph is left behind.
+func (n *Neo4J) loadProvFrom(conn neo4j.Conn, kind GraphKind, iteration uint, provCond string, stream func(fi.ProvSink) error) error {
+
+	tx, err := conn.Begin()
 	if err != nil {
 		return err
 	}
 
-	// During first run: create constraints and indexes.
-	if iteration == 0 {
+	sink := &neoSink{
+		n:         n,
+		conn:      conn,
+		iteration: iteration,
+		cond:      provCond,
+		params: map[string]interface{}{
+			"analysis":  n.Analysis,
+			"kind":      string(kind),
+			"run":       iteration,
+			"condition": provCond,
+		},
+		isGoal: make(map[string]bool),
+		isRule: make(map[string]bool),
+	}
 
-		_, err = n.Conn1.ExecNeo(`
-			CREATE CONSTRAINT ON (goal:Goal) ASSERT goal.id IS UNIQUE;
-		`, nil)
-		if err != nil {
-			return err
-		}
+	err = stream(sink)
+	if err == nil {
+		err = sink.finish()
+	}
 
-		_, err = n.Conn1.ExecNeo(`
-			CREATE INDEX ON :Goal(run);
-		`, nil)
-		if err != nil {
-			return err
+	if err != nil {
+
+		rbErr := tx.Rollback()
+		if rbErr != nil {
+			return fmt.Errorf("%v (rollback failed as well: %v)", err, rbErr)
 		}
-	}
 
-	// Verify number of inserted elements.
-	if int64(len(provData.Goals)) != resCnt {
-		return fmt.Errorf("Run %d: inserted number of goals (%d) does not equal number of antecedent provenance goals (%d)", iteration, resCnt, len(provData.Goals))
+		return err
 	}
 
-	resCnt = 0
+	return tx.Commit()
+}
 
-	stmtRule, err := n.Conn1.PrepareNeo(`
-		CREATE (n:Rule {id: {id}, run: {run}, condition: {condition}, label: {label}, table: {table}, type: {type}});
-	`)
+// flush imports all buffered goals, rules, and edges.
+// Goals and rules go first, so that edges find their
+// endpoints.
+func (s *neoSink) flush() error {
+
+	// Create all buffered goal nodes.
+	resCnt, err := s.n.execBatched(s.conn, `
+		UNWIND {batch} AS g
+		CREATE (goal:Goal {uid: g.uid, id: g.id, analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, label: g.label, table: g.table, time: g.time, condition_holds: g.condition_holds});
+	`, s.goals, s.params, "nodes-created")
 	if err != nil {
 		return err
 	}
+	s.goalsCreated += resCnt
+	s.goals = s.goals[:0]
+
+	// Create all buffered rule nodes.
+	resCnt, err = s.n.execBatched(s.conn, `
+		UNWIND {batch} AS r
+		CREATE (n:Rule {uid: r.uid, id: r.id, analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, label: r.label, table: r.table, type: r.type});
+	`, s.rules, s.params, "nodes-created")
+	if err != nil {
+		return err
+	}
+	s.rulesCreated += resCnt
+	s.rules = s.rules[:0]
+
+	// Create all buffered edge relations.
+	resCnt, err = s.n.execBatched(s.conn, `
+		UNWIND {batch} AS e
+		MATCH (goal:Goal {uid: e.from, kind: {kind}, run: {run}, condition: {condition}})
+		MATCH (rule:Rule {uid: e.to, kind: {kind}, run: {run}, condition: {condition}})
+		MERGE (goal)-[:DUETO {analysis: {analysis}}]->(rule);
+	`, s.goalRuleEdges, s.params, "relationships-created")
+	if err != nil {
+		return err
+	}
+	s.edgesCreated += resCnt
+	s.goalRuleEdges = s.goalRuleEdges[:0]
+
+	resCnt, err = s.n.execBatched(s.conn, `
+		UNWIND {batch} AS e
+		MATCH (rule:Rule {uid: e.from, kind: {kind}, run: {run}, condition: {condition}})
+		MATCH (goal:Goal {uid: e.to, kind: {kind}, run: {run}, condition: {condition}})
+		MERGE (rule)-[:DUETO {analysis: {analysis}}]->(goal);
+	`, s.ruleGoalEdges, s.params, "relationships-created")
+	if err != nil {
+		return err
+	}
+	s.edgesCreated += resCnt
+	s.ruleGoalEdges = s.ruleGoalEdges[:0]
 
-	for j := range provData.Rules {
+	return nil
+}
 
-		// Create a rule node.
-		res, err := stmtRule.ExecNeo(map[string]interface{}{
-			"id":        provData.Rules[j].ID,
-			"run":       iteration,
-			"condition": provCond,
-			"label":     provData.Rules[j].Label,
-			"table":     provData.Rules[j].Table,
-			"type":      provData.Rules[j].Type,
-		})
-		if err != nil {
-			return err
-		}
+// full reports whether a batch is ready to be imported.
+func (s *neoSink) full() bool {
+	return (len(s.goals) + len(s.rules) + len(s.goalRuleEdges) + len(s.ruleGoalEdges)) >= importBatchSize
+}
 
-		// Collect affected rows information.
-		rowsAff, err := res.RowsAffected()
-		if err != nil {
-			return err
-		}
-		resCnt += rowsAff
+// AddGoal buffers goal for import.
+func (s *neoSink) AddGoal(goal *fi.Goal) error {
+
+	s.numGoals++
+	s.isGoal[goal.ID] = true
+
+	s.goals = append(s.goals, map[string]interface{}{
+		"uid":             nodeUID(s.n.Analysis, goal.ID),
+		"id":              goal.ID,
+		"label":           goal.Label,
+		"table":           goal.Table,
+		"time":            goal.Time,
+		"condition_holds": goal.CondHolds,
+	})
+
+	if s.full() {
+		return s.flush()
 	}
 
-	err = stmtRule.Close()
-	if err != nil {
-		return err
+	return nil
+}
+
+// AddRule buffers rule for import.
+func (s *neoSink) AddRule(rule *fi.Rule) error {
+
+	s.numRules++
+	s.isRule[rule.ID] = true
+
+	s.rules = append(s.rules, map[string]interface{}{
+		"uid":   nodeUID(s.n.Analysis, rule.ID),
+		"id":    rule.ID,
+		"label": rule.Label,
+		"table": rule.Table,
+		"type":  rule.Type,
+	})
+
+	if s.full() {
+		return s.flush()
 	}
 
-	// During first run: create constraints and indexes.
-	if iteration == 0 {
+	return nil
+}
 
-		_, err = n.Conn1.ExecNeo(`
-			CREATE CONSTRAINT ON (rule:Rule) ASSERT rule.id IS UNIQUE;
-		`, nil)
-		if err != nil {
-			return err
-		}
+// AddEdge buffers edge for import. Edges preceding
+// one of their endpoints are deferred until the end.
+func (s *neoSink) AddEdge(edge *fi.Edge) error {
 
-		_, err = n.Conn1.ExecNeo(`
-			CREATE INDEX ON :Rule(run);
-		`, nil)
-		if err != nil {
-			return err
-		}
+	s.numEdges++
+
+	fromKnown := s.isGoal[edge.From] || s.isRule[edge.From]
+	toKnown := s.isGoal[edge.To] || s.isRule[edge.To]
+
+	if !fromKnown || !toKnown {
+		s.deferred = append(s.deferred, *edge)
+		return nil
 	}
 
-	// Verify number of inserted elements.
-	if int64(len(provData.Rules)) != resCnt {
-		return fmt.Errorf("Run %d: inserted number of rules (%d) does not equal number of antecedent provenance rules (%d)", iteration, resCnt, len(provData.Rules))
+	s.bufferEdge(edge)
+
+	if s.full() {
+		return s.flush()
 	}
 
-	resCnt = 0
+	return nil
+}
 
-	stmtGoalRuleEdge, err := n.Conn1.PrepareNeo(`
-		MATCH (goal:Goal {id: {from}, run: {run}, condition: {condition}})
-		MATCH (rule:Rule {id: {to}, run: {run}, condition: {condition}})
-		MERGE (goal)-[:DUETO]->(rule);
-	`)
+// bufferEdge sorts edge by the type of its source.
+func (s *neoSink) bufferEdge(edge *fi.Edge) {
+
+	e := map[string]interface{}{
+		"from": nodeUID(s.n.Analysis, edge.From),
+		"to":   nodeUID(s.n.Analysis, edge.To),
+	}
+
+	if s.isGoal[edge.From] {
+		s.goalRuleEdges = append(s.goalRuleEdges, e)
+	} else {
+		s.ruleGoalEdges = append(s.ruleGoalEdges, e)
+	}
+}
+
+// finish imports deferred edges and all remaining
+// buffered elements, and verifies that every streamed
+// element has been created.
+func (s *neoSink) finish() error {
+
+	for j := range s.deferred {
+		s.bufferEdge(&s.deferred[j])
+	}
+	s.deferred = nil
+
+	err := s.flush()
 	if err != nil {
 		return err
 	}
 
-	stmtRuleGoalEdge, err := n.Conn2.PrepareNeo(`
-		MATCH (rule:Rule {id: {from}, run: {run}, condition: {condition}})
-		MATCH (goal:Goal {id: {to}, run: {run}, condition: {condition}})
-		MERGE (rule)-[:DUETO]->(goal);
-	`)
+	// Verify number of inserted elements.
+	if s.numGoals != s.goalsCreated {
+		return fmt.Errorf("Run %d: inserted number of goals (%d) does not equal number of %s provenance goals (%d)", s.iteration, s.goalsCreated, s.cond, s.numGoals)
+	}
+
+	if s.numRules != s.rulesCreated {
+		return fmt.Errorf("Run %d: inserted number of rules (%d) does not equal number of %s provenance rules (%d)", s.iteration, s.rulesCreated, s.cond, s.numRules)
+	}
+
+	if s.numEdges != s.edgesCreated {
+		return fmt.Errorf("Run %d: inserted number of edges (%d) does not equal number of %s provenance edges (%d)", s.iteration, s.edgesCreated, s.cond, s.numEdges)
+	}
+
+	return nil
+}
+
+// pullProvGraph fetches all goals, rules, and edges of
+// specified graph into an in-process graph.
+func (n *Neo4J) pullProvGraph(conn neo4j.Conn, kind GraphKind, run uint, condition string) (*provGraph, error) {
+
+	prov := newProvGraph()
+
+	nodesRaw, err := conn.QueryNeo(`
+		MATCH (n {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
+		RETURN n
+		ORDER BY ID(n);
+	`, map[string]interface{}{
+		"analysis":  n.Analysis,
+		"kind":      string(kind),
+		"run":       run,
+		"condition": condition,
+	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	nodesAll, _, err := nodesRaw.All()
+	if err != nil {
+		return nil, err
+	}
+
+	err = nodesRaw.Close()
+	if err != nil {
+		return nil, err
 	}
 
-	for j := range provData.Edges {
+	for i := range nodesAll {
 
-		var res neo4j.Result
+		node := nodesAll[i][0].(graph.Node)
 
-		// Create an edge relation.
-		if strings.Contains(provData.Edges[j].From, "goal") {
-			res, err = stmtGoalRuleEdge.ExecNeo(map[string]interface{}{
-				"from":      provData.Edges[j].From,
-				"to":        provData.Edges[j].To,
-				"run":       iteration,
-				"condition": provCond,
-			})
+		if node.Labels[0] == "Goal" {
+			prov.addGoal(goalFromNode(node))
 		} else {
-			res, err = stmtRuleGoalEdge.ExecNeo(map[string]interface{}{
-				"from":      provData.Edges[j].From,
-				"to":        provData.Edges[j].To,
-				"run":       iteration,
-				"condition": provCond,
-			})
-		}
-		if err != nil {
-			return err
+			prov.addRule(ruleFromNode(node))
 		}
+	}
 
-		// Track number of created relationships.
-		stats := res.Metadata()["stats"].(map[string]interface{})
-		resCnt += stats["relationships-created"].(int64)
+	edgesRaw, err := conn.QueryNeo(`
+		MATCH (from {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})-[e:DUETO]->(to {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
+		RETURN from.id, to.id
+		ORDER BY ID(e);
+	`, map[string]interface{}{
+		"analysis":  n.Analysis,
+		"kind":      string(kind),
+		"run":       run,
+		"condition": condition,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	err = stmtGoalRuleEdge.Close()
+	edgesAll, _, err := edgesRaw.All()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = stmtRuleGoalEdge.Close()
+	err = edgesRaw.Close()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Verify number of inserted elements.
-	if int64(len(provData.Edges)) != resCnt {
-		return fmt.Errorf("Run %d: inserted number of edges (%d) does not equal number of antecedent provenance edges (%d)", iteration, resCnt, len(provData.Edges))
+	for i := range edgesAll {
+		prov.addEdge(edgesAll[i][0].(string), edgesAll[i][1].(string))
 	}
 
-	return nil
+	return prov, nil
 }
 
 // markConditionHolds walks the provenance graph of
 // specified run and condition and marks goals depending
 // on whether the specified condition holds.
-func (n *Neo4J) markConditionHolds(iteration uint, provCond string) error {
+func (n *Neo4J) markConditionHolds(conn neo4j.Conn, iteration uint, provCond string) error {
 
-	stmtMarkCond, err := n.Conn1.PrepareNeo(`
-		MATCH (g:Goal {run: {run}, condition: {condition}})-[*1]->(r:Rule {run: {run}, condition: {condition}})
-		WHERE (:Goal {run: {run}, condition: {condition}, table: {condition}})-[*1]->(:Rule {run: {run}, condition: {condition}, table: {condition}})-[*1]->(g) AND NOT ()-->(:Goal {run: {run}, condition: {condition}, table: {condition}})-[*1]->(:Rule {run: {run}, condition: {condition}, table: {condition}})-[*1]->(g)
+	stmtMarkCond, err := conn.PrepareNeo(`
+		MATCH (g:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})-[*1]->(r:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
+		WHERE (:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, table: {condition}})-[*1]->(:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, table: {condition}})-[*1]->(g) AND NOT ()-->(:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, table: {condition}})-[*1]->(:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, table: {condition}})-[*1]->(g)
 		WITH g.table AS rule
 
-		MATCH (n:Goal {run: {run}, condition: {condition}})
+		MATCH (n:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
 		WHERE n.table = {condition} OR n.table = rule
 		SET n.condition_holds = true
 	`)
 
 	_, err = stmtMarkCond.ExecNeo(map[string]interface{}{
+		"analysis":  n.Analysis,
+		"kind":      string(KindRaw),
 		"run":       iteration,
 		"condition": provCond,
 	})
@@ -243,212 +428,137 @@ func (n *Neo4J) markConditionHolds(iteration uint, provCond string) error {
 	return nil
 }
 
-// LoadRawProvenance
-func (n *Neo4J) LoadRawProvenance() error {
+// LoadRawProvenance imports the raw provenance graphs
+// of all runs from the elements source streams.
+func (n *Neo4J) LoadRawProvenance(source fi.ProvStreamer) error {
 
 	fmt.Printf("Loading raw provenance data...\n")
 
-	for i := range n.Runs {
-
-		// Load antecedent provenance.
-		fmt.Printf("\t[%d] Antecedent provenance... ", n.Runs[i].Iteration)
-		err := n.loadProv(n.Runs[i].Iteration, "pre", n.Runs[i].PreProv)
-		if err != nil {
-			return err
-		}
-		fmt.Printf("done\n")
-
-		// Taint goals for which the antecedent holds.
-		err = n.markConditionHolds(n.Runs[i].Iteration, "pre")
-		if err != nil {
-			return err
-		}
-
-		// Load consequent provenance.
-		fmt.Printf("\t[%d] Consequent provenance... ", n.Runs[i].Iteration)
-		err = n.loadProv(n.Runs[i].Iteration, "post", n.Runs[i].PostProv)
-		if err != nil {
-			return err
-		}
-		fmt.Printf("done\n")
-
-		// Taint goals for which the consequent holds.
-		err = n.markConditionHolds(n.Runs[i].Iteration, "post")
-		if err != nil {
-			return err
-		}
+	// Replace whatever an earlier analysis under
+	// the same ID left behind in the database.
+	err := n.DeleteAnalysis(n.Analysis)
+	if err != nil {
+		return err
 	}
 
-	fmt.Println()
-
-	return nil
-}
-
-// PullPrePostProv
-func (n *Neo4J) PullPrePostProv() ([]*gographviz.Graph, []*gographviz.Graph, []*gographviz.Graph, []*gographviz.Graph, error) {
-
-	fmt.Printf("Pulling antecedent and consequent provenance... ")
-
-	preDots := make([]*gographviz.Graph, len(n.Runs))
-	postDots := make([]*gographviz.Graph, len(n.Runs))
-	preCleanDots := make([]*gographviz.Graph, len(n.Runs))
-	postCleanDots := make([]*gographviz.Graph, len(n.Runs))
-
-	// Query for imported correctness condition provenance.
-	stmtProv, err := n.Conn1.PrepareNeo(`
-		MATCH path = ({run: {run}, condition: {condition}})-[:DUETO*1]->({run: {run}, condition: {condition}})
-		RETURN path;
-	`)
+	err = n.registerAnalysis()
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return err
 	}
 
-	for i := range n.Runs {
+	progress := newImportProgress(2 * len(n.Runs))
+	defer progress.finish()
 
-		preEdges := make([]graph.Path, 0, 20)
-		postEdges := make([]graph.Path, 0, 20)
-		preCleanEdges := make([]graph.Path, 0, 20)
-		postCleanEdges := make([]graph.Path, 0, 20)
+	err = n.forEach(len(n.Runs), func(conn neo4j.Conn, i int) error {
 
-		preEdgesRaw, err := stmtProv.QueryNeo(map[string]interface{}{
-			"run":       n.Runs[i].Iteration,
-			"condition": "pre",
-		})
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+		for _, cond := range []string{"pre", "post"} {
 
-		preEdgesRows, _, err := preEdgesRaw.All()
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+			iter := n.Runs[i].Iteration
 
-		for p := range preEdgesRows {
+			err := n.loadProvFrom(conn, KindRaw, iter, cond, func(sink fi.ProvSink) error {
+				return source.StreamProv(iter, cond, progress.sink(sink))
+			})
+			if err != nil {
+				return err
+			}
 
-			// Type-assert raw edge into well-defined struct.
-			edge := preEdgesRows[p][0].(graph.Path)
+			// Taint goals for which the condition holds.
+			err = n.markConditionHolds(conn, iter, cond)
+			if err != nil {
+				return err
+			}
 
-			// Append to slice of edges.
-			preEdges = append(preEdges, edge)
+			progress.graphDone(iter, cond)
 		}
 
-		// Pass to DOT string generator.
-		preDot, err := createDOT(preEdges, "pre")
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		err = preEdgesRaw.Close()
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+	fmt.Println()
 
-		postEdgesRaw, err := stmtProv.QueryNeo(map[string]interface{}{
-			"run":       n.Runs[i].Iteration,
-			"condition": "post",
-		})
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+	return nil
+}
 
-		postEdgesRows, _, err := postEdgesRaw.All()
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+// pullDOT queries all edges of specified graph
+// and creates the corresponding DOT diagram.
+func (n *Neo4J) pullDOT(conn neo4j.Conn, kind GraphKind, run uint, condition string) (*gographviz.Graph, error) {
 
-		for p := range postEdgesRows {
+	edges := make([]graph.Path, 0, 20)
 
-			// Type-assert raw edge into well-defined struct.
-			edge := postEdgesRows[p][0].(graph.Path)
+	// Query for imported correctness condition provenance.
+	edgesRaw, err := conn.QueryNeo(`
+		MATCH path = ({analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})-[:DUETO*1]->({analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
+		RETURN path;
+	`, map[string]interface{}{
+		"analysis":  n.Analysis,
+		"kind":      string(kind),
+		"run":       run,
+		"condition": condition,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-			// Append to slice of edges.
-			postEdges = append(postEdges, edge)
-		}
+	edgesRows, _, err := edgesRaw.All()
+	if err != nil {
+		return nil, err
+	}
 
-		// Pass to DOT string generator.
-		postDot, err := createDOT(postEdges, "post")
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+	err = edgesRaw.Close()
+	if err != nil {
+		return nil, err
+	}
 
-		err = postEdgesRaw.Close()
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+	for p := range edgesRows {
 
-		preCleanEdgesRaw, err := stmtProv.QueryNeo(map[string]interface{}{
-			"run":       (1000 + n.Runs[i].Iteration),
-			"condition": "pre",
-		})
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+		// Type-assert raw edge into well-defined struct.
+		edge := edgesRows[p][0].(graph.Path)
 
-		preCleanEdgesRows, _, err := preCleanEdgesRaw.All()
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+		// Append to slice of edges.
+		edges = append(edges, edge)
+	}
 
-		for p := range preCleanEdgesRows {
+	// Pass to DOT string generator.
+	return createDOT(edges, condition)
+}
 
-			// Type-assert raw edge into well-defined struct.
-			edge := preCleanEdgesRows[p][0].(graph.Path)
+// PullPrePostProv
+func (n *Neo4J) PullPrePostProv() ([]*gographviz.Graph, []*gographviz.Graph, []*gographviz.Graph, []*gographviz.Graph, error) {
 
-			// Append to slice of edges.
-			preCleanEdges = append(preCleanEdges, edge)
-		}
+	fmt.Printf("Pulling antecedent and consequent provenance... ")
 
-		// Pass to DOT string generator.
-		preCleanDot, err := createDOT(preCleanEdges, "pre")
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+	preDots := make([]*gographviz.Graph, len(n.Runs))
+	postDots := make([]*gographviz.Graph, len(n.Runs))
+	preCleanDots := make([]*gographviz.Graph, len(n.Runs))
+	postCleanDots := make([]*gographviz.Graph, len(n.Runs))
 
-		err = preCleanEdgesRaw.Close()
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+	err := n.forEach(len(n.Runs), func(conn neo4j.Conn, i int) error {
 
-		postCleanEdgesRaw, err := stmtProv.QueryNeo(map[string]interface{}{
-			"run":       (1000 + n.Runs[i].Iteration),
-			"condition": "post",
-		})
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
+		var err error
+		run := n.Runs[i].Iteration
 
-		postCleanEdgesRows, _, err := postCleanEdgesRaw.All()
+		preDots[i], err = n.pullDOT(conn, KindRaw, run, "pre")
 		if err != nil {
-			return nil, nil, nil, nil, err
-		}
-
-		for p := range postCleanEdgesRows {
-
-			// Type-assert raw edge into well-defined struct.
-			edge := postCleanEdgesRows[p][0].(graph.Path)
-
-			// Append to slice of edges.
-			postCleanEdges = append(postCleanEdges, edge)
+			return err
 		}
 
-		// Pass to DOT string generator.
-		postCleanDot, err := createDOT(postCleanEdges, "post")
+		postDots[i], err = n.pullDOT(conn, KindRaw, run, "post")
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return err
 		}
 
-		err = postCleanEdgesRaw.Close()
+		preCleanDots[i], err = n.pullDOT(conn, KindClean, run, "pre")
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return err
 		}
 
-		preDots[i] = preDot
-		postDots[i] = postDot
-		preCleanDots[i] = preCleanDot
-		postCleanDots[i] = postCleanDot
-	}
+		postCleanDots[i], err = n.pullDOT(conn, KindClean, run, "post")
 
-	err = stmtProv.Close()
+		return err
+	})
 	if err != nil {
 		return nil, nil, nil, nil, err
 	}
@@ -2,73 +2,38 @@ package graphing
 
 import (
 	"fmt"
-	"strings"
-
-	"os/exec"
 
+	neo4j "github.com/johnnadratowski/golang-neo4j-bolt-driver"
 	graph "github.com/johnnadratowski/golang-neo4j-bolt-driver/structures/graph"
+	fi "github.com/numbleroot/nemo/faultinjectors"
 )
 
-// cleanCopyProv
-func (n *Neo4J) cleanCopyProv(iter uint, condition string) error {
-
-	newID := 1000 + iter
+// cleanCopyProv copies all paths between goals of
+// specified raw graph into a clean graph.
+func (n *Neo4J) cleanCopyProv(conn neo4j.Conn, iter uint, condition string) error {
 
-	exportQuery := `CALL apoc.export.cypher.query("
-		MATCH path = (g1:Goal {run: ###RUN###, condition: '###CONDITION###'})-[*0..]->(g2:Goal {run: ###RUN###, condition: '###CONDITION###'})
-		RETURN path;
-		", "/tmp/clean-prov", {format: "cypher-shell", cypherFormat: "create"})
-		YIELD time
-		RETURN time;
-	`
-
-	exportQuery = strings.Replace(exportQuery, "###RUN###", fmt.Sprintf("%d", iter), -1)
-	exportQuery = strings.Replace(exportQuery, "###CONDITION###", condition, -1)
-	_, err := n.Conn1.ExecNeo(exportQuery, nil)
+	prov, err := n.pullProvGraph(conn, KindRaw, iter, condition)
 	if err != nil {
 		return err
 	}
 
-	// Replace run ID part of node ID in saved queries.
-	sedIDLong := fmt.Sprintf("s/`id`:\"run_%d/`id`:\"run_%d/g", iter, newID)
-	cmd := exec.Command("sudo", "docker", "exec", "graphdb", "sed", "-i", sedIDLong, "/tmp/clean-prov")
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return err
-	}
-
-	if strings.TrimSpace(string(out)) != "" {
-		return fmt.Errorf("Wrong return value from docker-compose exec sed diffprov run ID command: %s", out)
-	}
-
-	// Replace run ID in saved queries.
-	sedIDShort := fmt.Sprintf("s/`run`:%d/`run`:%d/g", iter, newID)
-	cmd = exec.Command("sudo", "docker", "exec", "graphdb", "sed", "-i", sedIDShort, "/tmp/clean-prov")
-	out, err = cmd.CombinedOutput()
-	if err != nil {
-		return err
-	}
-
-	if strings.TrimSpace(string(out)) != "" {
-		return fmt.Errorf("Wrong return value from docker-compose exec sed diffprov run ID command: %s", out)
-	}
-
-	// Import modified difference graph as new one.
-	_, err = n.Conn1.ExecNeo(`CALL apoc.cypher.runFile("/tmp/clean-prov", {statistics: false});`, nil)
-	if err != nil {
-		return err
-	}
+	// Keep everything lying on a path from one goal to
+	// another and replace run ID part of node IDs.
+	clean := prov.between(func(goal *fi.Goal) bool {
+		return true
+	}).renamed(idPrefix(KindRaw, iter, condition), idPrefix(KindClean, iter, condition))
 
-	return nil
+	// Import modified graph as new one.
+	return n.loadProv(conn, KindClean, iter, condition, clean.toProvData())
 }
 
 // collapseNextChains
-func (n *Neo4J) collapseNextChains(iter uint, condition string) error {
+func (n *Neo4J) collapseNextChains(conn neo4j.Conn, iter uint, condition string) error {
 
-	run := (1000 + iter)
+	run := iter
 
-	stmtCollapseNext, err := n.Conn2.PrepareNeo(`
-		MATCH path = (r1:Rule {run: {run}, condition: {condition}, type: "next"})-[*1..]->(g:Goal {run: {run}, condition: {condition}})-[*1..]->(r2:Rule {run: {run}, condition: {condition}, type: "next"})
+	stmtCollapseNext, err := conn.PrepareNeo(`
+		MATCH path = (r1:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, type: "next"})-[*1..]->(g:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})-[*1..]->(r2:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, type: "next"})
 		WHERE all(node IN nodes(path) WHERE node.type = "next" OR not(exists(node.type)))
 		WITH path, nodes(path) AS nodesRaw, length(path) AS len
 		UNWIND nodesRaw AS node
@@ -81,6 +46,8 @@ func (n *Neo4J) collapseNextChains(iter uint, condition string) error {
 	}
 
 	nextPaths, err := stmtCollapseNext.QueryNeo(map[string]interface{}{
+		"analysis":  n.Analysis,
+		"kind":      string(KindClean),
 		"run":       run,
 		"condition": condition,
 	})
@@ -143,8 +110,8 @@ func (n *Neo4J) collapseNextChains(iter uint, condition string) error {
 	}
 
 	// Find predecessor relations to chain.
-	stmtPred, err := n.Conn1.PrepareNeo(`
-		MATCH (pred:Goal {run: {run}, condition: {condition}})-[*1]->(root:Rule {run: {run}, condition: {condition}})
+	stmtPred, err := conn.PrepareNeo(`
+		MATCH (pred:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})-[*1]->(root:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
 		WHERE ID(root) = {rootID}
 		WITH collect(ID(pred)) AS preds
 		RETURN preds;
@@ -158,6 +125,8 @@ func (n *Neo4J) collapseNextChains(iter uint, condition string) error {
 	for i := range nextChains {
 
 		predsRaw, err := stmtPred.QueryNeo(map[string]interface{}{
+			"analysis":  n.Analysis,
+			"kind":      string(KindClean),
 			"run":       run,
 			"condition": condition,
 			"rootID":    nextChainIDs[i][0],
@@ -195,8 +164,8 @@ func (n *Neo4J) collapseNextChains(iter uint, condition string) error {
 	}
 
 	// Find all "outwards" relations of chain.
-	stmtSucc, err := n.Conn2.PrepareNeo(`
-		MATCH (leaf:Rule {run: {run}, condition: {condition}})-[*1]->(succ:Goal {run: {run}, condition: {condition}})
+	stmtSucc, err := conn.PrepareNeo(`
+		MATCH (leaf:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})-[*1]->(succ:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
 		WHERE ID(leaf) = {leafID}
 		WITH collect(ID(succ)) AS succs
 		RETURN succs;
@@ -210,6 +179,8 @@ func (n *Neo4J) collapseNextChains(iter uint, condition string) error {
 	for i := range nextChains {
 
 		succsRaw, err := stmtSucc.QueryNeo(map[string]interface{}{
+			"analysis":  n.Analysis,
+			"kind":      string(KindClean),
 			"run":       run,
 			"condition": condition,
 			"leafID":    nextChainIDs[i][(len(nextChainIDs[i]) - 1)],
@@ -249,37 +220,28 @@ func (n *Neo4J) collapseNextChains(iter uint, condition string) error {
 	for i := range nextChains {
 
 		label := fmt.Sprintf("%s_collapsed", nextChains[i][0].Properties["table"])
-		id := fmt.Sprintf("run_%d_%s_%s_%d", run, condition, label, i)
+		id := fmt.Sprintf("%s%s_%d", idPrefix(KindClean, run, condition), label, i)
 
-		var predsIDs string
+		predsIDs := make([]interface{}, len(preds[i]))
 		for j := range preds[i] {
-
-			if predsIDs == "" {
-				predsIDs = fmt.Sprintf("[%d", preds[i][j])
-			} else {
-				predsIDs = fmt.Sprintf("%s, %d", predsIDs, preds[i][j])
-			}
+			predsIDs[j] = preds[i][j]
 		}
-		predsIDs = fmt.Sprintf("%s]", predsIDs)
 
-		var succsIDs string
+		succsIDs := make([]interface{}, len(succs[i]))
 		for j := range succs[i] {
-
-			if succsIDs == "" {
-				succsIDs = fmt.Sprintf("[%d", succs[i][j])
-			} else {
-				succsIDs = fmt.Sprintf("%s, %d", succsIDs, succs[i][j])
-			}
+			succsIDs[j] = succs[i][j]
 		}
-		succsIDs = fmt.Sprintf("%s]", succsIDs)
 
 		// Create new nodes representing the intent of the
 		// captured @next chains.
-		_, err := n.Conn1.ExecNeo(`
-		CREATE (repl:Rule {run: {run}, condition: {condition}, id: {id}, label: {label}, table: {table}, type: "collapsed"});
+		_, err := conn.ExecNeo(`
+		CREATE (repl:Rule {uid: {uid}, id: {id}, analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, label: {label}, table: {table}, type: "collapsed"});
 		`, map[string]interface{}{
+			"analysis":  n.Analysis,
+			"kind":      string(KindClean),
 			"run":       run,
 			"condition": condition,
+			"uid":       nodeUID(n.Analysis, id),
 			"id":        id,
 			"label":     label,
 			"table":     nextChains[i][0].Properties["table"],
@@ -290,19 +252,20 @@ func (n *Neo4J) collapseNextChains(iter uint, condition string) error {
 
 		// Connect newly created collapsed next node with
 		// predecessors and successors.
-		addPredsSuccsQuery := `
-			MATCH (pred:Goal {run: ###RUN###, condition: "###CONDITION###"}), (coll:Rule {run: ###RUN###, condition: "###CONDITION###", id: "###ID###", type: "collapsed"}), (succ:Goal {run: ###RUN###, condition: "###CONDITION###"})
-			WHERE ID(pred) IN ###PRED_IDs### AND ID(succ) IN ###SUCC_IDs###
-			MERGE (pred)-[:DUETO]->(coll)
-			MERGE (coll)-[:DUETO]->(succ);
-		`
-		addPredsSuccsQuery = strings.Replace(addPredsSuccsQuery, "###RUN###", fmt.Sprintf("%d", run), -1)
-		addPredsSuccsQuery = strings.Replace(addPredsSuccsQuery, "###CONDITION###", condition, -1)
-		addPredsSuccsQuery = strings.Replace(addPredsSuccsQuery, "###ID###", id, -1)
-		addPredsSuccsQuery = strings.Replace(addPredsSuccsQuery, "###PRED_IDs###", predsIDs, -1)
-		addPredsSuccsQuery = strings.Replace(addPredsSuccsQuery, "###SUCC_IDs###", succsIDs, -1)
-
-		_, err = n.Conn2.ExecNeo(addPredsSuccsQuery, nil)
+		_, err = conn.ExecNeo(`
+			MATCH (pred:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}}), (coll:Rule {uid: {uid}, type: "collapsed"}), (succ:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
+			WHERE ID(pred) IN {predsIDs} AND ID(succ) IN {succsIDs}
+			MERGE (pred)-[:DUETO {analysis: {analysis}}]->(coll)
+			MERGE (coll)-[:DUETO {analysis: {analysis}}]->(succ);
+		`, map[string]interface{}{
+			"analysis":  n.Analysis,
+			"kind":      string(KindClean),
+			"run":       run,
+			"condition": condition,
+			"uid":       nodeUID(n.Analysis, id),
+			"predsIDs":  predsIDs,
+			"succsIDs":  succsIDs,
+		})
 		if err != nil {
 			return err
 		}
@@ -310,30 +273,31 @@ func (n *Neo4J) collapseNextChains(iter uint, condition string) error {
 
 	// Delete extracted next chain.
 	stmtDelChainRaw := `
-		MATCH path = (r:Rule {run: {run}, condition: {condition}, type: "next"})-[*1..]->(g:Goal {run: {run}, condition: {condition}})-[*1..]->(l:Rule {run: {run}, condition: {condition}, type: "next"})
-		WHERE all(node IN nodes(path) WHERE ID(node) IN ###CHAIN_IDs###)
+		MATCH path = (r:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, type: "next"})-[*1..]->(g:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})-[*1..]->(l:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}, type: "next"})
+		WHERE all(node IN nodes(path) WHERE ID(node) IN {chainIDs})
 		WITH path, nodes(path) AS nodes, length(path) AS len
 		ORDER BY len DESC
 		UNWIND nodes AS node
 		DETACH DELETE node;
 	`
 
-	// Create string containing all IDs to delete in Cypher format.
-	deleteIDs := make([]string, 0, len(nextChainsNodes))
+	// Collect all IDs to delete as query parameter.
+	deleteIDs := make([]interface{}, 0, len(nextChainsNodes))
 	for id := range nextChainsNodes {
-		deleteIDs = append(deleteIDs, fmt.Sprintf("%d", id))
+		deleteIDs = append(deleteIDs, id)
 	}
-	deleteIDsString := strings.Join(deleteIDs, ", ")
-	stmtDelChainRaw = strings.Replace(stmtDelChainRaw, "###CHAIN_IDs###", fmt.Sprintf("[%s]", deleteIDsString), -1)
 
-	stmtDelChain, err := n.Conn1.PrepareNeo(stmtDelChainRaw)
+	stmtDelChain, err := conn.PrepareNeo(stmtDelChainRaw)
 	if err != nil {
 		return err
 	}
 
 	_, err = stmtDelChain.ExecNeo(map[string]interface{}{
+		"analysis":  n.Analysis,
+		"kind":      string(KindClean),
 		"run":       run,
 		"condition": condition,
+		"chainIDs":  deleteIDs,
 	})
 	if err != nil {
 		return err
@@ -352,33 +316,33 @@ func (n *Neo4J) SimplifyProv(iters []uint) error {
 
 	fmt.Printf("Preprocessing provenance graphs... ")
 
-	for i := range iters {
+	err := n.forEach(len(iters), func(conn neo4j.Conn, i int) error {
 
-		// Clean-copy antecedent provenance (run: 1000+).
-		err := n.cleanCopyProv(iters[i], "pre")
+		// Clean-copy antecedent provenance.
+		err := n.cleanCopyProv(conn, iters[i], "pre")
 		if err != nil {
 			return err
 		}
 
-		// Clean-copy consequent provenance (run: 1000+).
-		err = n.cleanCopyProv(iters[i], "post")
+		// Clean-copy consequent provenance.
+		err = n.cleanCopyProv(conn, iters[i], "post")
 		if err != nil {
 			return err
 		}
 
-		// Do preprocessing over graphs of run 1000+:
+		// Do preprocessing over clean graphs:
 
 		// Collapse @next chains in antecedent provenance.
-		err = n.collapseNextChains(iters[i], "pre")
+		err = n.collapseNextChains(conn, iters[i], "pre")
 		if err != nil {
 			return err
 		}
 
 		// Collapse @next chains in consequent provenance.
-		err = n.collapseNextChains(iters[i], "post")
-		if err != nil {
-			return err
-		}
+		return n.collapseNextChains(conn, iters[i], "post")
+	})
+	if err != nil {
+		return err
 	}
 
 	fmt.Printf("done\n\n")
@@ -0,0 +1,117 @@
+package graphing
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Constants.
+
+// progressInterval is the time between two
+// reports of import progress.
+const progressInterval = 5 * time.Second
+
+// Structs.
+
+// importProgress counts the provenance elements
+// imported so far and periodically reports them
+// while an import takes long.
+type importProgress struct {
+	goals  int64
+	rules  int64
+	edges  int64
+	graphs int64
+	total  int
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// countingSink counts the elements it passes on.
+type countingSink struct {
+	progress *importProgress
+	next     fi.ProvSink
+}
+
+// Functions.
+
+// newImportProgress starts reporting the progress of
+// importing total provenance graphs.
+func newImportProgress(total int) *importProgress {
+
+	p := &importProgress{
+		total: total,
+		stop:  make(chan struct{}),
+	}
+
+	p.wg.Add(1)
+	go func() {
+
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				fmt.Printf("\t... %d goals, %d rules, %d edges imported, %d of %d graphs complete\n",
+					atomic.LoadInt64(&p.goals), atomic.LoadInt64(&p.rules), atomic.LoadInt64(&p.edges),
+					atomic.LoadInt64(&p.graphs), p.total)
+			}
+		}
+	}()
+
+	return p
+}
+
+// sink wraps next to count all elements passed to it.
+func (p *importProgress) sink(next fi.ProvSink) fi.ProvSink {
+
+	return &countingSink{
+		progress: p,
+		next:     next,
+	}
+}
+
+// graphDone notes that the provenance of specified run
+// and condition has been imported completely.
+func (p *importProgress) graphDone(iteration uint, condition string) {
+
+	atomic.AddInt64(&p.graphs, 1)
+
+	if condition == "pre" {
+		fmt.Printf("\t[%d] Antecedent provenance... done\n", iteration)
+	} else {
+		fmt.Printf("\t[%d] Consequent provenance... done\n", iteration)
+	}
+}
+
+// finish stops reporting.
+func (p *importProgress) finish() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// AddGoal
+func (c *countingSink) AddGoal(goal *fi.Goal) error {
+	atomic.AddInt64(&c.progress.goals, 1)
+	return c.next.AddGoal(goal)
+}
+
+// AddRule
+func (c *countingSink) AddRule(rule *fi.Rule) error {
+	atomic.AddInt64(&c.progress.rules, 1)
+	return c.next.AddRule(rule)
+}
+
+// AddEdge
+func (c *countingSink) AddEdge(edge *fi.Edge) error {
+	atomic.AddInt64(&c.progress.edges, 1)
+	return c.next.AddEdge(edge)
+}
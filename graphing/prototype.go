@@ -2,79 +2,17 @@ package graphing
 
 import (
 	"fmt"
-)
 
-// extractProtos extracts the intersection-prototype
-// and union-prototype from all iterations.
-func (n *Neo4J) extractProtos(iters []uint, condition string) ([]string, []string, error) {
+	neo4j "github.com/johnnadratowski/golang-neo4j-bolt-driver"
+)
 
-	stmtCondRules, err := n.Conn1.PrepareNeo(`
-		MATCH path = (root:Goal {run: {run}, condition: {condition}})-[*1]->(r1:Rule {run: {run}, condition: {condition}})-[*1..]->(r2:Rule {run: {run}, condition: {condition}})
-		OPTIONAL MATCH (g:Goal {run: {run}, condition: "pre", condition_holds: true})
-		WITH path, root, collect(g) AS existsSuccess, length(path) AS len
-		WHERE size(existsSuccess) > 0 AND not(()-->(root))
-		WITH path, len
-		ORDER BY len DESC
-		WITH collect(nodes(path)) AS nodes
-		WITH reduce(output = [], node IN nodes | output + node) AS nodes
-		WITH filter(node IN nodes WHERE exists(node.type)) AS rules
-		UNWIND rules AS rule
-		WITH collect(DISTINCT rule.table) AS rules
-		RETURN rules;
-    `)
-	if err != nil {
-		return nil, nil, err
-	}
+// buildProtos computes the intersection-prototype and
+// the union-prototype from the rule chains of all iterations
+// that eventually achieved their condition.
+func buildProtos(iterProv [][]string, achvdCond int, condition string) ([]string, []string) {
 
-	achvdCond := 0
 	interProto := make([]string, 0, 10)
 	unionProto := make([]string, 0, 10)
-	iterProv := make([][]string, len(iters))
-
-	for i := range iters {
-
-		// Request all rule labels as long as the
-		// execution eventually achieved its condition.
-		condRules, err := stmtCondRules.QueryNeo(map[string]interface{}{
-			"run":       (1000 + iters[i]),
-			"condition": condition,
-		})
-		if err != nil {
-			return nil, nil, err
-		}
-
-		condAllRules, _, err := condRules.All()
-		if err != nil {
-			return nil, nil, err
-		}
-
-		err = condRules.Close()
-		if err != nil {
-			return nil, nil, err
-		}
-
-		for j := range condAllRules {
-
-			for k := range condAllRules[j] {
-
-				rulesRaw := condAllRules[j][k].([]interface{})
-				rules := make([]string, len(rulesRaw))
-
-				for l := range rules {
-					rules[l] = rulesRaw[l].(string)
-				}
-
-				if len(rules) > 0 {
-
-					// Count how many times the antecedent was achieved.
-					achvdCond += 1
-
-					// Add rules slice to tracking structure.
-					iterProv[i] = rules
-				}
-			}
-		}
-	}
 
 	// Initially, set first chain as longest.
 	longest := len(iterProv[0])
@@ -129,19 +67,130 @@ func (n *Neo4J) extractProtos(iters []uint, condition string) ([]string, []strin
 		}
 	}
 
+	return interProto, unionProto
+}
+
+// condRules returns all rule labels of specified run's
+// clean provenance as long as the execution eventually
+// achieved its condition.
+func (n *Neo4J) condRules(conn neo4j.Conn, iter uint, condition string) ([]string, error) {
+
+	stmtCondRules, err := conn.PrepareNeo(`
+		MATCH path = (root:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})-[*1]->(r1:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})-[*1..]->(r2:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
+		OPTIONAL MATCH (g:Goal {analysis: {analysis}, kind: {kind}, run: {run}, condition: "pre", condition_holds: true})
+		WITH path, root, collect(g) AS existsSuccess, length(path) AS len
+		WHERE size(existsSuccess) > 0 AND not(()-->(root))
+		WITH path, len
+		ORDER BY len DESC
+		WITH collect(nodes(path)) AS nodes
+		WITH reduce(output = [], node IN nodes | output + node) AS nodes
+		WITH filter(node IN nodes WHERE exists(node.type)) AS rules
+		UNWIND rules AS rule
+		WITH collect(DISTINCT rule.table) AS rules
+		RETURN rules;
+    `)
+	if err != nil {
+		return nil, err
+	}
+
+	condRules, err := stmtCondRules.QueryNeo(map[string]interface{}{
+		"analysis":  n.Analysis,
+		"kind":      string(KindClean),
+		"run":       iter,
+		"condition": condition,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	condAllRules, _, err := condRules.All()
+	if err != nil {
+		return nil, err
+	}
+
+	err = condRules.Close()
+	if err != nil {
+		return nil, err
+	}
+
 	err = stmtCondRules.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var iterRules []string
+
+	for j := range condAllRules {
+
+		for k := range condAllRules[j] {
+
+			rulesRaw := condAllRules[j][k].([]interface{})
+			rules := make([]string, len(rulesRaw))
+
+			for l := range rules {
+				rules[l] = rulesRaw[l].(string)
+			}
+
+			if len(rules) > 0 {
+				iterRules = rules
+			}
+		}
+	}
+
+	return iterRules, nil
+}
+
+// extractProtos extracts the intersection-prototype
+// and union-prototype from all iterations.
+func (n *Neo4J) extractProtos(iters []uint, condition string) ([]string, []string, error) {
+
+	iterProv := make([][]string, len(iters))
+
+	err := n.forEach(len(iters), func(conn neo4j.Conn, i int) error {
+
+		var err error
+		iterProv[i], err = n.condRules(conn, iters[i], condition)
+
+		return err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// Count how many times the condition was achieved.
+	achvdCond := 0
+	for i := range iterProv {
+		if len(iterProv[i]) > 0 {
+			achvdCond += 1
+		}
+	}
+
+	interProto, unionProto := buildProtos(iterProv, achvdCond, condition)
+
 	return interProto, unionProto, nil
 }
 
+// missingRules figures out the difference in rules
+// between prototype and failed run's rules.
+func missingRules(proto []string, failedRules map[string]bool) []string {
+
+	missing := make([]string, 0, 3)
+
+	for p := range proto {
+
+		if !failedRules[proto[p]] {
+			missing = append(missing, fmt.Sprintf("<code>%s</code>", proto[p]))
+		}
+	}
+
+	return missing
+}
+
 // missingFrom
-func (n *Neo4J) missingFrom(proto []string, failedIter uint, condition string) ([]string, error) {
+func (n *Neo4J) missingFrom(conn neo4j.Conn, proto []string, failedIter uint, condition string) ([]string, error) {
 
-	stmtMissRules, err := n.Conn1.PrepareNeo(`
-		MATCH (r:Rule {run: {run}, condition: {condition}})
+	stmtMissRules, err := conn.PrepareNeo(`
+		MATCH (r:Rule {analysis: {analysis}, kind: {kind}, run: {run}, condition: {condition}})
 		WITH collect(DISTINCT r.table) AS rules
 		RETURN rules;
     `)
@@ -150,7 +199,9 @@ func (n *Neo4J) missingFrom(proto []string, failedIter uint, condition string) (
 	}
 
 	missRules, err := stmtMissRules.QueryNeo(map[string]interface{}{
-		"run":       (1000 + failedIter),
+		"analysis":  n.Analysis,
+		"kind":      string(KindClean),
+		"run":       failedIter,
 		"condition": condition,
 	})
 	if err != nil {
@@ -186,23 +237,12 @@ func (n *Neo4J) missingFrom(proto []string, failedIter uint, condition string) (
 		}
 	}
 
-	// Figure out the difference in rules
-	// between prototype and failed run's rules.
-	missing := make([]string, 0, 3)
-
-	for p := range proto {
-
-		if !failedRules[proto[p]] {
-			missing = append(missing, fmt.Sprintf("<code>%s</code>", proto[p]))
-		}
-	}
-
 	err = stmtMissRules.Close()
 	if err != nil {
 		return nil, err
 	}
 
-	return missing, nil
+	return missingRules(proto, failedRules), nil
 }
 
 // CreatePrototypes
@@ -223,23 +263,25 @@ func (n *Neo4J) CreatePrototypes(iters []uint, failedIters []uint) ([]string, []
 	interProtoMiss := make([][]string, len(failedIters))
 	unionProtoMiss := make([][]string, len(failedIters))
 
-	for i := range failedIters {
+	err = n.forEach(len(failedIters), func(conn neo4j.Conn, i int) error {
+
+		var err error
 
 		// Collect all nodes missing in the failed execution's consequent
 		// provenance that are part of the intersection-prototype.
-		interMiss, err := n.missingFrom(interProto, failedIters[i], "post")
+		interProtoMiss[i], err = n.missingFrom(conn, interProto, failedIters[i], "post")
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return err
 		}
-		interProtoMiss[i] = interMiss
 
 		// Collect all nodes missing in the failed execution's consequent
 		// provenance that are part of the union-prototype.
-		unionMiss, err := n.missingFrom(unionProto, failedIters[i], "post")
-		if err != nil {
-			return nil, nil, nil, nil, err
-		}
-		unionProtoMiss[i] = unionMiss
+		unionProtoMiss[i], err = n.missingFrom(conn, unionProto, failedIters[i], "post")
+
+		return err
+	})
+	if err != nil {
+		return nil, nil, nil, nil, err
 	}
 
 	for i := range interProto {
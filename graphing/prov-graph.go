@@ -0,0 +1,761 @@
+package graphing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	graph "github.com/johnnadratowski/golang-neo4j-bolt-driver/structures/graph"
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Structs.
+
+// provGraph is an in-process representation of one
+// provenance graph, i.e., all goals, rules, and edges
+// of one run for one condition.
+type provGraph struct {
+	goals map[string]*fi.Goal
+	rules map[string]*fi.Rule
+	order []string
+	succs map[string][]string
+	preds map[string][]string
+}
+
+// Functions.
+
+// newProvGraph returns an empty provenance graph.
+func newProvGraph() *provGraph {
+
+	return &provGraph{
+		goals: make(map[string]*fi.Goal),
+		rules: make(map[string]*fi.Rule),
+		order: make([]string, 0, 20),
+		succs: make(map[string][]string),
+		preds: make(map[string][]string),
+	}
+}
+
+// addGoal adds a copy of the supplied goal to the
+// graph. It reports false if the ID is already taken.
+func (g *provGraph) addGoal(goal fi.Goal) bool {
+
+	if g.has(goal.ID) {
+		return false
+	}
+
+	g.goals[goal.ID] = &goal
+	g.order = append(g.order, goal.ID)
+
+	return true
+}
+
+// addRule adds a copy of the supplied rule to the
+// graph. It reports false if the ID is already taken.
+func (g *provGraph) addRule(rule fi.Rule) bool {
+
+	if g.has(rule.ID) {
+		return false
+	}
+
+	g.rules[rule.ID] = &rule
+	g.order = append(g.order, rule.ID)
+
+	return true
+}
+
+// addEdge connects a goal to a rule or a rule to a
+// goal. Edges are only created once and only between
+// existing nodes of alternating type. It reports
+// whether a new edge was created.
+func (g *provGraph) addEdge(from string, to string) bool {
+
+	if !((g.isGoal(from) && g.isRule(to)) || (g.isRule(from) && g.isGoal(to))) {
+		return false
+	}
+
+	for _, succ := range g.succs[from] {
+		if succ == to {
+			return false
+		}
+	}
+
+	g.succs[from] = append(g.succs[from], to)
+	g.preds[to] = append(g.preds[to], from)
+
+	return true
+}
+
+// has
+func (g *provGraph) has(id string) bool {
+	return g.isGoal(id) || g.isRule(id)
+}
+
+// isGoal
+func (g *provGraph) isGoal(id string) bool {
+	_, found := g.goals[id]
+	return found
+}
+
+// isRule
+func (g *provGraph) isRule(id string) bool {
+	_, found := g.rules[id]
+	return found
+}
+
+// isNextRule
+func (g *provGraph) isNextRule(id string) bool {
+	rule, found := g.rules[id]
+	return found && (rule.Type == "next")
+}
+
+// removeNode deletes a node and all its edges.
+func (g *provGraph) removeNode(id string) {
+
+	for _, succ := range g.succs[id] {
+		g.preds[succ] = without(g.preds[succ], id)
+	}
+
+	for _, pred := range g.preds[id] {
+		g.succs[pred] = without(g.succs[pred], id)
+	}
+
+	delete(g.succs, id)
+	delete(g.preds, id)
+	delete(g.goals, id)
+	delete(g.rules, id)
+	g.order = without(g.order, id)
+}
+
+// without returns ids without elem.
+func without(ids []string, elem string) []string {
+
+	res := ids[:0]
+	for i := range ids {
+
+		if ids[i] != elem {
+			res = append(res, ids[i])
+		}
+	}
+
+	return res
+}
+
+// provGraphFromData builds a provenance graph from
+// the goals, rules, and edges of a fault injector.
+func provGraphFromData(provData *fi.ProvData) *provGraph {
+
+	g := newProvGraph()
+
+	if provData == nil {
+		return g
+	}
+
+	for i := range provData.Goals {
+		g.addGoal(provData.Goals[i])
+	}
+
+	for i := range provData.Rules {
+		g.addRule(provData.Rules[i])
+	}
+
+	for i := range provData.Edges {
+		g.addEdge(provData.Edges[i].From, provData.Edges[i].To)
+	}
+
+	return g
+}
+
+// toProvData converts the graph back into the
+// goals, rules, and edges representation.
+func (g *provGraph) toProvData() *fi.ProvData {
+
+	provData := &fi.ProvData{
+		Goals: make([]fi.Goal, 0, len(g.goals)),
+		Rules: make([]fi.Rule, 0, len(g.rules)),
+		Edges: make([]fi.Edge, 0, len(g.order)),
+	}
+
+	for _, id := range g.order {
+
+		if g.isGoal(id) {
+			provData.Goals = append(provData.Goals, *g.goals[id])
+		} else {
+			provData.Rules = append(provData.Rules, *g.rules[id])
+		}
+
+		for _, succ := range g.succs[id] {
+			provData.Edges = append(provData.Edges, fi.Edge{
+				From: id,
+				To:   succ,
+			})
+		}
+	}
+
+	return provData
+}
+
+// node returns the node identified by id in the
+// form the graph database driver would hand it out.
+func (g *provGraph) node(id string) graph.Node {
+
+	goal, isGoal := g.goals[id]
+	if isGoal {
+
+		return graph.Node{
+			Labels: []string{"Goal"},
+			Properties: map[string]interface{}{
+				"id":              goal.ID,
+				"label":           goal.Label,
+				"table":           goal.Table,
+				"time":            goal.Time,
+				"condition_holds": goal.CondHolds,
+			},
+		}
+	}
+
+	rule := g.rules[id]
+
+	return graph.Node{
+		Labels: []string{"Rule"},
+		Properties: map[string]interface{}{
+			"id":    rule.ID,
+			"label": rule.Label,
+			"table": rule.Table,
+			"type":  rule.Type,
+		},
+	}
+}
+
+// goalFromNode converts a goal node handed out by
+// the graph database driver into a goal.
+func goalFromNode(node graph.Node) fi.Goal {
+
+	return fi.Goal{
+		ID:        node.Properties["id"].(string),
+		Label:     node.Properties["label"].(string),
+		Table:     node.Properties["table"].(string),
+		Time:      node.Properties["time"].(string),
+		CondHolds: node.Properties["condition_holds"].(bool),
+	}
+}
+
+// ruleFromNode converts a rule node handed out by
+// the graph database driver into a rule.
+func ruleFromNode(node graph.Node) fi.Rule {
+
+	return fi.Rule{
+		ID:    node.Properties["id"].(string),
+		Label: node.Properties["label"].(string),
+		Table: node.Properties["table"].(string),
+		Type:  node.Properties["type"].(string),
+	}
+}
+
+// paths returns one path of length one per edge,
+// suitable for handing to the DOT generators.
+func (g *provGraph) paths() []graph.Path {
+
+	paths := make([]graph.Path, 0, len(g.order))
+
+	for _, from := range g.order {
+
+		for _, to := range g.succs[from] {
+			paths = append(paths, graph.Path{
+				Nodes: []graph.Node{g.node(from), g.node(to)},
+			})
+		}
+	}
+
+	return paths
+}
+
+// renamed returns a copy of the graph in which the
+// ID prefix oldPrefix is replaced by newPrefix.
+func (g *provGraph) renamed(oldPrefix string, newPrefix string) *provGraph {
+
+	rename := func(id string) string {
+
+		if strings.HasPrefix(id, oldPrefix) {
+			return newPrefix + strings.TrimPrefix(id, oldPrefix)
+		}
+
+		return id
+	}
+
+	c := newProvGraph()
+
+	for _, id := range g.order {
+
+		if g.isGoal(id) {
+			goal := *g.goals[id]
+			goal.ID = rename(goal.ID)
+			c.addGoal(goal)
+		} else {
+			rule := *g.rules[id]
+			rule.ID = rename(rule.ID)
+			c.addRule(rule)
+		}
+	}
+
+	for _, id := range g.order {
+
+		for _, succ := range g.succs[id] {
+			c.addEdge(rename(id), rename(succ))
+		}
+	}
+
+	return c
+}
+
+// reach returns all nodes reachable from the start
+// nodes (including them) following the supplied edges.
+func reach(start []string, edges map[string][]string) map[string]bool {
+
+	seen := make(map[string]bool)
+	stack := append([]string(nil), start...)
+
+	for len(stack) > 0 {
+
+		id := stack[(len(stack) - 1)]
+		stack = stack[:(len(stack) - 1)]
+
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		stack = append(stack, edges[id]...)
+	}
+
+	return seen
+}
+
+// between returns the subgraph made up of all nodes
+// and edges lying on a path that starts and ends in
+// a goal selected by keep. Paths of length zero, i.e.,
+// single selected goals, are included.
+func (g *provGraph) between(keep func(*fi.Goal) bool) *provGraph {
+
+	anchors := make([]string, 0, len(g.goals))
+	for _, id := range g.order {
+
+		if g.isGoal(id) && keep(g.goals[id]) {
+			anchors = append(anchors, id)
+		}
+	}
+
+	fwd := reach(anchors, g.succs)
+	bwd := reach(anchors, g.preds)
+
+	sub := newProvGraph()
+
+	for _, id := range g.order {
+
+		if fwd[id] && bwd[id] {
+
+			if g.isGoal(id) {
+				sub.addGoal(*g.goals[id])
+			} else {
+				sub.addRule(*g.rules[id])
+			}
+		}
+	}
+
+	for _, id := range g.order {
+
+		for _, succ := range g.succs[id] {
+
+			if fwd[id] && bwd[succ] {
+				sub.addEdge(id, succ)
+			}
+		}
+	}
+
+	return sub
+}
+
+// ruleTables returns the set of tables of all rules.
+func (g *provGraph) ruleTables() map[string]bool {
+
+	tables := make(map[string]bool)
+	for _, rule := range g.rules {
+		tables[rule.Table] = true
+	}
+
+	return tables
+}
+
+// holds reports whether any goal in the graph
+// is marked as satisfying the condition.
+func (g *provGraph) holds() bool {
+
+	for _, goal := range g.goals {
+
+		if goal.CondHolds {
+			return true
+		}
+	}
+
+	return false
+}
+
+// markConditionHolds marks all goals of the condition's
+// table as well as goals of tables directly derived
+// from a root condition goal as holding the condition.
+func (g *provGraph) markConditionHolds(condition string) {
+
+	tables := make(map[string]bool)
+
+	for _, id := range g.order {
+
+		goal, isGoal := g.goals[id]
+		if !isGoal || (len(g.succs[id]) == 0) {
+			continue
+		}
+
+		derived := false
+		rooted := true
+
+		for _, ruleID := range g.preds[id] {
+
+			rule := g.rules[ruleID]
+			if rule.Table != condition {
+				continue
+			}
+
+			for _, condID := range g.preds[ruleID] {
+
+				if g.goals[condID].Table != condition {
+					continue
+				}
+
+				derived = true
+
+				if len(g.preds[condID]) > 0 {
+					rooted = false
+				}
+			}
+		}
+
+		if derived && rooted {
+			tables[goal.Table] = true
+		}
+	}
+
+	if len(tables) == 0 {
+		return
+	}
+
+	for _, goal := range g.goals {
+
+		if (goal.Table == condition) || tables[goal.Table] {
+			goal.CondHolds = true
+		}
+	}
+}
+
+// walk enumerates all cycle-free paths starting
+// at start for which step allows each next node.
+// Every path for which accept returns true is
+// handed to visit.
+func (g *provGraph) walk(start string, step func(string) bool, accept func([]string) bool, visit func([]string)) {
+
+	onPath := make(map[string]bool)
+	path := []string{start}
+	onPath[start] = true
+
+	var descend func()
+	descend = func() {
+
+		if accept(path) {
+			visit(append([]string(nil), path...))
+		}
+
+		last := path[(len(path) - 1)]
+		for _, succ := range g.succs[last] {
+
+			if onPath[succ] || !step(succ) {
+				continue
+			}
+
+			onPath[succ] = true
+			path = append(path, succ)
+
+			descend()
+
+			path = path[:(len(path) - 1)]
+			onPath[succ] = false
+		}
+	}
+
+	descend()
+}
+
+// longestFirst sorts paths by descending length,
+// keeping the order of equally long ones.
+func longestFirst(paths [][]string) {
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i]) > len(paths[j])
+	})
+}
+
+// nextChains returns all chains of @next rules and
+// the goals in between them, longest first.
+func (g *provGraph) nextChains() [][]string {
+
+	chains := make([][]string, 0, 10)
+
+	for _, id := range g.order {
+
+		if !g.isNextRule(id) {
+			continue
+		}
+
+		g.walk(id, func(succ string) bool {
+			return g.isGoal(succ) || g.isNextRule(succ)
+		}, func(path []string) bool {
+			return (len(path) > 1) && g.isNextRule(path[(len(path)-1)])
+		}, func(path []string) {
+			chains = append(chains, path)
+		})
+	}
+
+	longestFirst(chains)
+
+	return chains
+}
+
+// collapseNextChains replaces each top-level chain
+// of @next rules by one rule of type "collapsed".
+func (g *provGraph) collapseNextChains(idPrefix string) {
+
+	// Create structure to track top-level @next chains.
+	nextChains := make([][]string, 0, 5)
+
+	// Create map to quickly check node containment in path.
+	nextChainsNodes := make(map[string]bool)
+
+	for _, chain := range g.nextChains() {
+
+		newChain := false
+		for _, id := range chain {
+
+			if !nextChainsNodes[id] {
+				newChain = true
+			}
+		}
+
+		if newChain {
+
+			nextChains = append(nextChains, chain)
+
+			for _, id := range chain {
+				nextChainsNodes[id] = true
+			}
+		}
+	}
+
+	// Find predecessor and successor goals of each chain.
+	preds := make([][]string, len(nextChains))
+	succs := make([][]string, len(nextChains))
+
+	for i := range nextChains {
+		preds[i] = append([]string(nil), g.preds[nextChains[i][0]]...)
+		succs[i] = append([]string(nil), g.succs[nextChains[i][(len(nextChains[i])-1)]]...)
+	}
+
+	for i := range nextChains {
+
+		table := g.rules[nextChains[i][0]].Table
+		label := table + "_collapsed"
+		id := fmt.Sprintf("%s%s_%d", idPrefix, label, i)
+
+		// Create new node representing the intent
+		// of the captured @next chain.
+		g.addRule(fi.Rule{
+			ID:    id,
+			Label: label,
+			Table: table,
+			Type:  "collapsed",
+		})
+
+		// Connect newly created collapsed next node with
+		// predecessors and successors, if both exist.
+		if (len(preds[i]) > 0) && (len(succs[i]) > 0) {
+
+			for _, pred := range preds[i] {
+				g.addEdge(pred, id)
+			}
+
+			for _, succ := range succs[i] {
+				g.addEdge(id, succ)
+			}
+		}
+	}
+
+	// Delete extracted next chains.
+	for _, id := range append([]string(nil), g.order...) {
+
+		if nextChainsNodes[id] {
+			g.removeNode(id)
+		}
+	}
+}
+
+// protoRules returns the distinct tables of all rules on
+// paths from a root goal to a rule, ordered by their first
+// occurrence when going through paths longest first.
+func (g *provGraph) protoRules() []string {
+
+	paths := make([][]string, 0, 10)
+
+	for _, id := range g.order {
+
+		if !g.isGoal(id) || (len(g.preds[id]) > 0) {
+			continue
+		}
+
+		g.walk(id, func(succ string) bool {
+			return true
+		}, func(path []string) bool {
+			return (len(path) > 2) && g.isRule(path[(len(path)-1)])
+		}, func(path []string) {
+			paths = append(paths, path)
+		})
+	}
+
+	longestFirst(paths)
+
+	seen := make(map[string]bool)
+	rules := make([]string, 0, 10)
+
+	for _, path := range paths {
+
+		for _, id := range path {
+
+			if g.isRule(id) && !seen[g.rules[id].Table] {
+				rules = append(rules, g.rules[id].Table)
+				seen[g.rules[id].Table] = true
+			}
+		}
+	}
+
+	return rules
+}
+
+// longestFromRoots returns, per node, the length of the
+// longest path starting at a root goal and ending in it.
+// Nodes not reachable from any root goal map to -1.
+func (g *provGraph) longestFromRoots() map[string]int {
+
+	longest := make(map[string]int)
+	visiting := make(map[string]bool)
+
+	var lookup func(id string) int
+	lookup = func(id string) int {
+
+		l, found := longest[id]
+		if found {
+			return l
+		}
+
+		if visiting[id] {
+			return -1
+		}
+		visiting[id] = true
+
+		l = -1
+		if g.isGoal(id) && (len(g.preds[id]) == 0) {
+			l = 0
+		}
+
+		for _, pred := range g.preds[id] {
+
+			predL := lookup(pred)
+			if (predL >= 0) && ((predL + 1) > l) {
+				l = predL + 1
+			}
+		}
+
+		visiting[id] = false
+		longest[id] = l
+
+		return l
+	}
+
+	for _, id := range g.order {
+		lookup(id)
+	}
+
+	return longest
+}
+
+// missingLeaves finds the rules that end the longest
+// paths from a root goal to a leaf goal, together with
+// all goals each of these rules points to.
+func (g *provGraph) missingLeaves() []*fi.Missing {
+
+	longest := g.longestFromRoots()
+
+	isLeaf := func(id string) bool {
+		return g.isGoal(id) && (len(g.succs[id]) == 0)
+	}
+
+	maxLen := -1
+	for _, id := range g.order {
+
+		if !g.isRule(id) || (longest[id] < 0) {
+			continue
+		}
+
+		for _, succ := range g.succs[id] {
+
+			if isLeaf(succ) && ((longest[id] + 1) > maxLen) {
+				maxLen = longest[id] + 1
+			}
+		}
+	}
+
+	missing := make([]*fi.Missing, 0, 2)
+
+	if maxLen < 0 {
+		return missing
+	}
+
+	for _, id := range g.order {
+
+		if !g.isRule(id) || ((longest[id] + 1) != maxLen) {
+			continue
+		}
+
+		hasLeaf := false
+		for _, succ := range g.succs[id] {
+
+			if isLeaf(succ) {
+				hasLeaf = true
+			}
+		}
+
+		if !hasLeaf {
+			continue
+		}
+
+		rule := *g.rules[id]
+		m := &fi.Missing{
+			Rule:  &rule,
+			Goals: make([]*fi.Goal, 0, 2),
+		}
+
+		for _, succ := range g.succs[id] {
+
+			goal := *g.goals[succ]
+			m.Goals = append(m.Goals, &goal)
+		}
+
+		missing = append(missing, m)
+	}
+
+	return missing
+}
@@ -0,0 +1,248 @@
+package graphing
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Constants.
+
+// Weights of the signals making up the score of a
+// root-cause candidate. They add up to one, so that
+// scores range from 0 to 1.
+const (
+	weightDistance  = 0.2
+	weightFault     = 0.4
+	weightAsync     = 0.2
+	weightFrequency = 0.2
+)
+
+// unreachable is the distance of a candidate that no
+// derivation connects to the consequent.
+const unreachable = -1
+
+// Functions.
+
+// distances returns the number of steps from the
+// consequent of g to each node of g reachable from it.
+func (g *provGraph) distances() map[string]int {
+
+	dist := make(map[string]int)
+	queue := make([]string, 0, len(g.order))
+
+	for _, id := range g.order {
+
+		if g.isGoal(id) && (g.goals[id].Table == "post") {
+			dist[id] = 0
+			queue = append(queue, id)
+		}
+	}
+
+	for len(queue) > 0 {
+
+		id := queue[0]
+		queue = queue[1:]
+
+		for _, succ := range g.succs[id] {
+
+			if _, seen := dist[succ]; !seen {
+				dist[succ] = dist[id] + 1
+				queue = append(queue, succ)
+			}
+		}
+	}
+
+	return dist
+}
+
+// candidateKey identifies a missing event across failed
+// runs by its rule and the time patterns of its goals.
+func candidateKey(m *fi.Missing) string {
+
+	patterns := make([]string, len(m.Goals))
+	for i := range m.Goals {
+		patterns[i] = timePattern(m.Goals[i])
+	}
+	sort.Strings(patterns)
+
+	return fmt.Sprintf("%s|%s|%s", m.Rule.Table, m.Rule.Type, strings.Join(patterns, "|"))
+}
+
+// candidateFaults returns the crashes in spec that hit a
+// node of missing event m, located in diff, and for network
+// events the omissions on the channel from its goals to the
+// goal its rule derives.
+func candidateFaults(spec *fi.FailureSpec, diff *provGraph, m *fi.Missing) ([]fi.CrashFailure, []fi.MessageLoss) {
+
+	crashes := make([]fi.CrashFailure, 0, 1)
+	omissions := make([]fi.MessageLoss, 0, 1)
+
+	if spec == nil {
+		return crashes, omissions
+	}
+
+	senders := make(map[string]bool)
+	for _, goal := range m.Goals {
+		senders[goalReceiver(goal.Label, goal.Table)] = true
+	}
+
+	receivers := make(map[string]bool)
+	for _, headID := range diff.preds[m.Rule.ID] {
+
+		if head := diff.goals[headID]; head != nil {
+			receivers[goalReceiver(head.Label, head.Table)] = true
+		}
+	}
+
+	if spec.Crashes != nil {
+		for _, crash := range *spec.Crashes {
+
+			if senders[crash.Node] || receivers[crash.Node] {
+				crashes = append(crashes, crash)
+			}
+		}
+	}
+
+	if (spec.Omissions != nil) && (m.Rule.Type == "async") {
+		for _, omission := range *spec.Omissions {
+
+			if senders[omission.From] && receivers[omission.To] {
+				omissions = append(omissions, omission)
+			}
+		}
+	}
+
+	return crashes, omissions
+}
+
+// rankRootCauses scores the missing events of all failed
+// runs, pulling their differential provenance via diff, and
+// returns the k highest-scoring ones per run, all if k is 0.
+func rankRootCauses(runs []*fi.Run, failedRuns []uint, missing [][]*fi.Missing, k int, diff func(run uint) (*provGraph, error)) ([][]*fi.RootCause, error) {
+
+	fmt.Printf("Ranking root-cause candidates of failed runs... ")
+
+	// Count in how many failed runs each event is missing.
+	missingIn := make(map[string]int)
+	for i := range missing {
+
+		keys := make(map[string]bool)
+		for _, m := range missing[i] {
+			keys[candidateKey(m)] = true
+		}
+
+		for key := range keys {
+			missingIn[key]++
+		}
+	}
+
+	candidates := make([][]*fi.RootCause, len(failedRuns))
+	maxDist := 0
+
+	for i := range failedRuns {
+
+		g, err := diff(failedRuns[i])
+		if err != nil {
+			return nil, err
+		}
+		dist := g.distances()
+
+		candidates[i] = make([]*fi.RootCause, len(missing[i]))
+		for j, m := range missing[i] {
+
+			crashes, omissions := candidateFaults(runs[failedRuns[i]].FailureSpec, g, m)
+
+			// Events not below the consequent have no
+			// depth and score no distance.
+			d, reachable := dist[m.Rule.ID]
+			if !reachable {
+				d = unreachable
+			}
+
+			c := &fi.RootCause{
+				Rule:      m.Rule,
+				Goals:     m.Goals,
+				Distance:  d,
+				MissingIn: missingIn[candidateKey(m)],
+				Scores:    &fi.RootCauseScores{},
+			}
+
+			if len(crashes) > 0 {
+				c.Crashes = crashes
+			}
+
+			if len(omissions) > 0 {
+				c.Omissions = omissions
+			}
+
+			if c.Distance > maxDist {
+				maxDist = c.Distance
+			}
+
+			candidates[i][j] = c
+		}
+	}
+
+	for i := range candidates {
+
+		for _, c := range candidates[i] {
+
+			// Deeper events are closer to where the
+			// failed run started to deviate.
+			if (maxDist > 0) && (c.Distance != unreachable) {
+				c.Scores.Distance = weightDistance * float64(c.Distance) / float64(maxDist)
+			}
+
+			if (len(c.Crashes) > 0) || (len(c.Omissions) > 0) {
+				c.Scores.Fault = weightFault
+			}
+
+			if c.Rule.Type == "async" {
+				c.Scores.Async = weightAsync
+			}
+
+			c.Scores.Frequency = weightFrequency * float64(c.MissingIn) / float64(len(failedRuns))
+
+			c.Score = c.Scores.Distance + c.Scores.Fault + c.Scores.Async + c.Scores.Frequency
+
+			// Round scores for presentation.
+			c.Scores.Distance = math.Round(c.Scores.Distance*1000) / 1000
+			c.Scores.Frequency = math.Round(c.Scores.Frequency*1000) / 1000
+			c.Score = math.Round(c.Score*1000) / 1000
+		}
+
+		sort.SliceStable(candidates[i], func(a, b int) bool {
+			return candidates[i][a].Score > candidates[i][b].Score
+		})
+
+		if (k > 0) && (len(candidates[i]) > k) {
+			candidates[i] = candidates[i][:k]
+		}
+
+		for r := range candidates[i] {
+			candidates[i][r].Rank = r + 1
+		}
+	}
+
+	fmt.Printf("done\n\n")
+
+	return candidates, nil
+}
+
+// RankRootCauses
+func (n *Neo4J) RankRootCauses(failedRuns []uint, missing [][]*fi.Missing, k int) ([][]*fi.RootCause, error) {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return rankRootCauses(n.Runs, failedRuns, missing, k, func(run uint) (*provGraph, error) {
+		return n.pullProvGraph(conn, KindDiff, run, "post")
+	})
+}
@@ -0,0 +1,130 @@
+package graphing
+
+import (
+	"reflect"
+	"testing"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Functions.
+
+// testDiffProv builds the differential provenance of a
+// failed run: the consequent derived over a network event
+// from a log entry on node c, which in turn follows one on
+// node b, and an acknowledgement unrelated to the consequent.
+func testDiffProv() *provGraph {
+
+	g := newProvGraph()
+
+	g.addGoal(testGoal("post", "post", "4", "b, 4"))
+	g.addRule(fi.Rule{ID: "r1", Label: "post", Table: "post", Type: "async"})
+	g.addGoal(testGoal("g1", "log", "3", "c, 3"))
+	g.addRule(fi.Rule{ID: "r2", Label: "log", Table: "log", Type: "next"})
+	g.addGoal(testGoal("g2", "log", "2", "b, 2"))
+	g.addRule(fi.Rule{ID: "r3", Label: "ack", Table: "ack", Type: "async"})
+	g.addGoal(testGoal("g3", "ack", "1", "b, 1"))
+
+	g.addEdge("post", "r1")
+	g.addEdge("r1", "g1")
+	g.addEdge("g1", "r2")
+	g.addEdge("r2", "g2")
+	g.addEdge("r3", "g3")
+
+	return g
+}
+
+func TestRankRootCauses(t *testing.T) {
+
+	g := testDiffProv()
+
+	tests := []struct {
+		name    string
+		missing []string
+		causes  []*fi.RootCause
+	}{
+		{
+			name:    "all signals",
+			missing: []string{"r1", "r2", "r3"},
+			causes: []*fi.RootCause{
+				{
+					Rank:      1,
+					Score:     0.867,
+					Scores:    &fi.RootCauseScores{Distance: 0.067, Fault: 0.4, Async: 0.2, Frequency: 0.2},
+					Distance:  1,
+					MissingIn: 1,
+					Crashes:   []fi.CrashFailure{{Node: "c", Time: 1}},
+				},
+				{
+					Rank:      2,
+					Score:     0.8,
+					Scores:    &fi.RootCauseScores{Distance: 0.2, Fault: 0.4, Frequency: 0.2},
+					Distance:  3,
+					MissingIn: 1,
+					Crashes:   []fi.CrashFailure{{Node: "c", Time: 1}},
+				},
+				{
+					Rank:      3,
+					Score:     0.4,
+					Scores:    &fi.RootCauseScores{Async: 0.2, Frequency: 0.2},
+					Distance:  unreachable,
+					MissingIn: 1,
+				},
+			},
+		},
+		{
+			name:    "no candidate below consequent",
+			missing: []string{"r3"},
+			causes: []*fi.RootCause{
+				{
+					Rank:      1,
+					Score:     0.4,
+					Scores:    &fi.RootCauseScores{Async: 0.2, Frequency: 0.2},
+					Distance:  unreachable,
+					MissingIn: 1,
+				},
+			},
+		},
+	}
+
+	runs := []*fi.Run{
+		{
+			Iteration: 0,
+			Status:    "failure",
+			FailureSpec: &fi.FailureSpec{
+				Crashes:   &[]fi.CrashFailure{{Node: "c", Time: 1}},
+				Omissions: &[]fi.MessageLoss{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+
+		missing := make([]*fi.Missing, len(tt.missing))
+		for i, id := range tt.missing {
+			missing[i] = &fi.Missing{Rule: g.rules[id], Goals: []*fi.Goal{g.goals[g.succs[id][0]]}}
+		}
+
+		causes, err := rankRootCauses(runs, []uint{0}, [][]*fi.Missing{missing}, 0, func(run uint) (*provGraph, error) {
+			return g, nil
+		})
+		if err != nil {
+			t.Fatalf("%s: ranking failed: %v", tt.name, err)
+		}
+
+		if len(causes[0]) != len(tt.causes) {
+			t.Fatalf("%s: ranked %d candidates, expected %d", tt.name, len(causes[0]), len(tt.causes))
+		}
+
+		for i, c := range causes[0] {
+
+			// Rules and goals are taken as they are.
+			c.Rule = nil
+			c.Goals = nil
+
+			if !reflect.DeepEqual(c, tt.causes[i]) {
+				t.Errorf("%s: candidate %d is %+v with %+v, expected %+v with %+v", tt.name, i, *c, *c.Scores, *tt.causes[i], *tt.causes[i].Scores)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package graphing
+
+import (
+	"fmt"
+
+	fi "github.com/numbleroot/nemo/faultinjectors"
+)
+
+// Functions.
+
+// provLabels returns the set of goal and
+// rule labels of a provenance graph.
+func provLabels(g *provGraph) map[string]bool {
+
+	labels := make(map[string]bool, len(g.goals)+len(g.rules))
+
+	for _, goal := range g.goals {
+		labels["goal:"+goal.Label] = true
+	}
+
+	for _, rule := range g.rules {
+		labels["rule:"+rule.Label] = true
+	}
+
+	return labels
+}
+
+// similarity returns the Jaccard index of two label sets:
+// the number of labels in both divided by the number of
+// labels in either. Two empty sets are equal.
+func similarity(a map[string]bool, b map[string]bool) float64 {
+
+	both := 0
+	for label := range a {
+
+		if b[label] {
+			both++
+		}
+	}
+
+	either := len(a) + len(b) - both
+	if either == 0 {
+		return 1.0
+	}
+
+	return float64(both) / float64(either)
+}
+
+// pairRuns pairs each failed run with the successful run
+// whose consequent provenance, pulled via prov, is most
+// similar to its own. Ties go to the earlier successful run.
+func pairRuns(successRuns []uint, failedRuns []uint, prov func(run uint) (*provGraph, error)) ([]*fi.RunPairing, error) {
+
+	fmt.Printf("Pairing failed runs with most similar successful runs... ")
+
+	if len(successRuns) == 0 {
+		fmt.Printf("no successful run\n\n")
+		return nil, nil
+	}
+
+	successLabels := make([]map[string]bool, len(successRuns))
+	for i := range successRuns {
+
+		g, err := prov(successRuns[i])
+		if err != nil {
+			return nil, err
+		}
+
+		successLabels[i] = provLabels(g)
+	}
+
+	pairings := make([]*fi.RunPairing, len(failedRuns))
+
+	for i := range failedRuns {
+
+		g, err := prov(failedRuns[i])
+		if err != nil {
+			return nil, err
+		}
+		failedLabels := provLabels(g)
+
+		for j := range successRuns {
+
+			sim := similarity(successLabels[j], failedLabels)
+
+			if (pairings[i] == nil) || (sim > pairings[i].Similarity) {
+				pairings[i] = &fi.RunPairing{
+					SuccessRun: successRuns[j],
+					Similarity: sim,
+				}
+			}
+		}
+	}
+
+	fmt.Printf("done\n\n")
+
+	return pairings, nil
+}
+
+// PairRuns
+func (n *Neo4J) PairRuns(successRuns []uint, failedRuns []uint) ([]*fi.RunPairing, error) {
+
+	conn, err := n.pool.OpenPool()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return pairRuns(successRuns, failedRuns, func(run uint) (*provGraph, error) {
+		return n.pullProvGraph(conn, KindRaw, run, "post")
+	})
+}
@@ -0,0 +1,100 @@
+package graphing
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	neo4j "github.com/johnnadratowski/golang-neo4j-bolt-driver"
+)
+
+// Structs.
+
+// errorList collects the errors of independent work
+// items processed concurrently, in item order.
+type errorList []error
+
+// Functions.
+
+// Error lists all contained errors.
+func (e errorList) Error() string {
+
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	msgs := make([]string, len(e))
+	for i := range e {
+		msgs[i] = e[i].Error()
+	}
+
+	return fmt.Sprintf("%d errors occurred:\n\t%s", len(e), strings.Join(msgs, "\n\t"))
+}
+
+// forEach calls work for each index in [0, num) on up to
+// n.Workers concurrent workers, each holding one pooled
+// connection. Work has to place its results at index i of
+// preallocated slices, which keeps the output ordering
+// independent of scheduling. Errors of all failed items
+// are returned together.
+func (n *Neo4J) forEach(num int, work func(conn neo4j.Conn, i int) error) error {
+
+	workers := n.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	if workers > num {
+		workers = num
+	}
+
+	items := make(chan int, num)
+	for i := 0; i < num; i++ {
+		items <- i
+	}
+	close(items)
+
+	errs := make([]error, num)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for w := 0; w < workers; w++ {
+
+		go func() {
+
+			defer wg.Done()
+
+			conn, err := n.pool.OpenPool()
+			if err != nil {
+
+				// Fail all items this worker would have taken.
+				for i := range items {
+					errs[i] = err
+				}
+
+				return
+			}
+			defer conn.Close()
+
+			for i := range items {
+				errs[i] = work(conn, i)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	var failed errorList
+	for i := range errs {
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+		}
+	}
+
+	if len(failed) > 0 {
+		return failed
+	}
+
+	return nil
+}
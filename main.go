@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
@@ -27,20 +31,31 @@ type FaultInjector interface {
 	GetRunsIters() []uint
 	GetSuccessRunsIters() []uint
 	GetFailedRunsIters() []uint
+	GetFingerprint() string
+	StreamProv(uint, string, fi.ProvSink) error
 }
 
 // GraphDatabase
 type GraphDatabase interface {
-	InitGraphDB(string, []*fi.Run) error
+	InitGraphDB(string, string, []*fi.Run) error
 	CloseDB() error
-	LoadRawProvenance() error
+	ListAnalyses() ([]*gr.Analysis, error)
+	DeleteAnalysis(string) error
+	RestoreCachedProv(string) (bool, error)
+	CacheProv(string) error
+	LoadRawProvenance(fi.ProvStreamer) error
 	SimplifyProv([]uint) error
-	CreateHazardAnalysis(string) ([]*gographviz.Graph, error)
+	CreateHazardAnalysis(fi.FS) ([]*gographviz.Graph, [][]*fi.HazardWindow, error)
 	CreatePrototypes([]uint, []uint) ([]string, [][]string, []string, [][]string, error)
 	PullPrePostProv() ([]*gographviz.Graph, []*gographviz.Graph, []*gographviz.Graph, []*gographviz.Graph, error)
-	CreateNaiveDiffProv(bool, []uint, *gographviz.Graph) ([]*gographviz.Graph, []*gographviz.Graph, [][]*fi.Missing, error)
-	GenerateCorrections() ([]string, error)
-	GenerateExtensions() (bool, []string, error)
+	PairRuns([]uint, []uint) ([]*fi.RunPairing, error)
+	AlignProv([]uint, []uint) ([]*fi.ProvAlignment, error)
+	RankRootCauses([]uint, [][]*fi.Missing, int) ([][]*fi.RootCause, error)
+	LocalizeFaults([]uint, []uint) ([]*fi.Suspiciousness, error)
+	CreateNaiveDiffProv(bool, []uint, []uint, []*gographviz.Graph) ([]*gographviz.Graph, []*gographviz.Graph, [][]*fi.Missing, []*gographviz.Graph, [][]*fi.Missing, error)
+	CreateMessageDiff([]uint, []uint, [][]*fi.Message) ([][]*fi.MessageDiff, error)
+	GenerateCorrections(uint) ([]string, error)
+	GenerateExtensions(uint) (bool, []string, error)
 }
 
 // Reporter
@@ -54,6 +69,7 @@ type Reporter interface {
 
 // DebugRun
 type DebugRun struct {
+	analysisID     string
 	workDir        string
 	allResultsDir  string
 	thisResultsDir string
@@ -62,38 +78,268 @@ type DebugRun struct {
 	reporter       Reporter
 }
 
+// inputName derives the name of a debug run from the
+// path to the fault injector output and returns it along
+// with the directory containing that output. For files,
+// the extension is dropped and the parent directory used.
+// Archives are used in place of a directory.
+func inputName(faultInjOut string) (string, string) {
+
+	if ext := fi.ArchiveExt(faultInjOut); ext != "" {
+		return strings.TrimSuffix(filepath.Base(faultInjOut), ext), faultInjOut
+	}
+
+	info, err := os.Stat(faultInjOut)
+	if (err == nil) && !info.IsDir() {
+
+		base := filepath.Base(faultInjOut)
+		return strings.TrimSuffix(base, filepath.Ext(base)), filepath.Dir(faultInjOut)
+	}
+
+	return filepath.Base(faultInjOut), faultInjOut
+}
+
+// analysisName derives the ID of the analysis of fault
+// injector output named runName at faultInjOut from the
+// fingerprint of its contents, so that different inputs
+// never share, and thus replace, an analysis, while
+// identical input keeps reusing its cached provenance.
+func analysisName(runName string, faultInjOut string, fingerprint string) string {
+
+	if fingerprint == "" {
+
+		absOut, err := filepath.Abs(faultInjOut)
+		if err != nil {
+			absOut = faultInjOut
+		}
+
+		sum := sha256.Sum256([]byte(absOut))
+		fingerprint = hex.EncodeToString(sum[:])
+	}
+
+	return fmt.Sprintf("%s-%s", runName, fingerprint[:12])
+}
+
+// referenceRun picks the successful run that corrections
+// and extensions are derived from: the one paired with
+// the most failed runs, ties going to the earlier run.
+// Without failed runs, it is the first successful run,
+// without successful runs, the first run.
+func referenceRun(successIters []uint, pairings []*fi.RunPairing) uint {
+
+	if len(successIters) == 0 {
+		return 0
+	}
+
+	counts := make(map[uint]int)
+	for i := range pairings {
+		counts[pairings[i].SuccessRun]++
+	}
+
+	ref := successIters[0]
+	for _, iter := range successIters {
+
+		if counts[iter] > counts[ref] {
+			ref = iter
+		}
+	}
+
+	return ref
+}
+
+// validateInput loads the fault injector output,
+// thereby running its validation pass, and reports
+// all problems found. It exits with status 1 if
+// there are any.
+func validateInput(faultInj FaultInjector, faultInjOut string) {
+
+	fmt.Printf("Validating fault injector output '%s'... ", faultInjOut)
+
+	err := faultInj.LoadOutput()
+	if problems, ok := err.(fi.Problems); ok {
+
+		fmt.Printf("found %d problem(s)\n\n", len(problems))
+		for i := range problems {
+			fmt.Printf("\t%s\n", problems[i])
+		}
+		fmt.Println()
+
+		os.Exit(1)
+	} else if err != nil {
+		fmt.Printf("failed\n\n")
+		log.Fatalf("Failed to load output from fault injector: %v", err)
+	}
+
+	fmt.Printf("done\n\n")
+	fmt.Printf("%d runs (%d successful, %d failed), no problems found.\n", len(faultInj.GetRunsIters()), len(faultInj.GetSuccessRunsIters()), len(faultInj.GetFailedRunsIters()))
+}
+
+// manageAnalyses lists or deletes analyses
+// stored in the graph database.
+func manageAnalyses(graphDB GraphDatabase, graphDBConn string, list bool, del string) {
+
+	err := graphDB.InitGraphDB(graphDBConn, "", nil)
+	if err != nil {
+		log.Fatalf("Failed to initialize connection to graph database: %v", err)
+	}
+	defer graphDB.CloseDB()
+
+	if del != "" {
+
+		fmt.Printf("Deleting analysis '%s'... ", del)
+		err = graphDB.DeleteAnalysis(del)
+		if err != nil {
+			log.Fatalf("Failed to delete analysis '%s': %v", del, err)
+		}
+		fmt.Printf("done\n\n")
+	}
+
+	if list {
+
+		analyses, err := graphDB.ListAnalyses()
+		if err != nil {
+			log.Fatalf("Failed to list analyses: %v", err)
+		}
+
+		fmt.Printf("Analyses stored in graph database:\n")
+		for i := range analyses {
+			fmt.Printf("\t%s\t(created %s, %d runs)\n", analyses[i].ID, analyses[i].Created, analyses[i].Runs)
+		}
+		fmt.Println()
+	}
+}
+
 func main() {
 
 	// Define which flags are supported.
-	faultInjOutFlag := flag.String("faultInjOut", "", "Specify file system path to output directory of fault injector.")
-	graphDBConnFlag := flag.String("graphDBConn", "bolt://127.0.0.1:7687", "Supply connection URI to dockerized graph database.")
+	faultInjFlag := flag.String("faultInj", "molly", "Select format of fault injector output: 'molly' (Molly fork with per-run provenance files), 'molly-upstream' (upstream Molly), 'nemo' (Nemo-native JSON/NDJSON), 'jepsen' (Jepsen/Elle histories), or 'otlp' (OpenTelemetry traces in OTLP JSON).")
+	faultInjOutFlag := flag.String("faultInjOut", "", "Specify file system path to output directory (or file, or .tar, .tar.gz, .tgz, or .zip archive) of fault injector.")
+	traceFailureFlag := flag.String("traceFailure", fi.DefaultTraceFailure, "Predicate labelling OTLP traces as failed if any span satisfies it: clauses 'field=value' or 'field!=value' over status, name, service, and attr.<key>, combined by '&&' and '||'.")
+	graphDBFlag := flag.String("graphDB", "neo4j", "Select graph database backend: 'neo4j' (dockerized Neo4J) or 'memory' (in-process, no Docker required).")
+	graphDBConnFlag := flag.String("graphDBConn", "bolt://127.0.0.1:7687", "Supply connection URI to graph database.")
+	graphDBUserFlag := flag.String("graphDBUser", "", "User name to authenticate with at the graph database.")
+	graphDBPasswordFlag := flag.String("graphDBPassword", os.Getenv("NEMO_GRAPHDB_PASSWORD"), "Password to authenticate with at the graph database (default: $NEMO_GRAPHDB_PASSWORD).")
+	graphDBTimeoutFlag := flag.Duration("graphDBTimeout", 60*time.Second, "Maximum time to wait for the graph database to accept connections.")
+	workersFlag := flag.Int("workers", 4, "Number of runs to process concurrently, each on its own graph database connection (Neo4J only).")
+	containersFlag := flag.String("containers", "docker-compose", "Select container management for Neo4J: 'docker-compose' (start and stop docker-compose.yml) or 'none' (connect to a running server).")
+	listAnalysesFlag := flag.Bool("listAnalyses", false, "List all analyses stored in the graph database and exit.")
+	forceReimportFlag := flag.Bool("force-reimport", false, "Import and simplify provenance even if it is cached for identical fault injector output.")
+	streamFlag := flag.Bool("stream", false, "Stream provenance files of Molly output into the graph database instead of holding them in memory (for very large provenance).")
+	symmetricDiffFlag := flag.Bool("symmetricDiff", false, "Also compute reverse differential provenance (failed - successful): derivations only failed runs made.")
+	rootCausesFlag := flag.Int("rootCauses", 5, "Number of top-ranked root-cause candidates to report per failed run (0 for all).")
+	deleteAnalysisFlag := flag.String("deleteAnalysis", "", "Delete the analysis with this ID from the graph database and exit.")
+
+	// 'nemo validate [flags]' only checks the fault
+	// injector output for inconsistencies.
+	validate := false
+	if (len(os.Args) > 1) && (os.Args[1] == "validate") {
+		validate = true
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	flag.Parse()
 
+	graphDBConn := *graphDBConnFlag
+
+	// Determine current working directory.
+	curDir, err := filepath.Abs(".")
+	if err != nil {
+		log.Fatalf("Failed obtaining absolute current directory: %v", err)
+	}
+
+	var graphDB GraphDatabase
+	switch *graphDBFlag {
+	case "neo4j":
+
+		var containers gr.ContainerManager
+		switch *containersFlag {
+		case "docker-compose":
+			containers = &gr.DockerCompose{
+				ComposeFile: "docker-compose.yml",
+				Sudo:        true,
+			}
+		case "none":
+		default:
+			log.Fatalf("Unknown container management '%s', choose 'docker-compose' or 'none'.", *containersFlag)
+		}
+
+		graphDB = &gr.Neo4J{
+			User:         *graphDBUserFlag,
+			Password:     *graphDBPasswordFlag,
+			Containers:   containers,
+			ReadyTimeout: *graphDBTimeoutFlag,
+			Workers:      *workersFlag,
+		}
+	case "memory":
+		graphDB = &gr.InMemory{
+			CacheDir: filepath.Join(curDir, "results", ".cache"),
+		}
+	default:
+		log.Fatalf("Unknown graph database backend '%s', choose 'neo4j' or 'memory'.", *graphDBFlag)
+	}
+
+	// Handle management of past analyses.
+	if *listAnalysesFlag || (*deleteAnalysisFlag != "") {
+		manageAnalyses(graphDB, graphDBConn, *listAnalysesFlag, *deleteAnalysisFlag)
+		return
+	}
+
 	// Extract and check for existence of required ones.
 	faultInjOut := *faultInjOutFlag
 	if faultInjOut == "" {
 		log.Fatal("Please provide a fault injection output directory to analyze.")
 	}
 
-	graphDBConn := *graphDBConnFlag
+	// Name this debug run after the fault injector output
+	// and find the directory holding its auxiliary files.
+	runName, faultInjDir := inputName(faultInjOut)
 
-	// Determine current working directory.
-	curDir, err := filepath.Abs(".")
-	if err != nil {
-		log.Fatalf("Failed obtaining absolute current directory: %v", err)
+	var faultInj FaultInjector
+	switch *faultInjFlag {
+	case "molly":
+		faultInj = &fi.Molly{
+			Run:       runName,
+			OutputDir: faultInjOut,
+			Stream:    *streamFlag,
+		}
+	case "molly-upstream":
+		faultInj = &fi.MollyUpstream{
+			Run:       runName,
+			OutputDir: faultInjOut,
+		}
+	case "nemo":
+		faultInj = &fi.Nemo{
+			Run:       runName,
+			InputPath: faultInjOut,
+		}
+	case "jepsen":
+		faultInj = &fi.Jepsen{
+			Run:       runName,
+			OutputDir: faultInjOut,
+		}
+	case "otlp":
+		faultInj = &fi.OTLP{
+			Run:       runName,
+			InputPath: faultInjOut,
+			Failure:   *traceFailureFlag,
+		}
+	default:
+		log.Fatalf("Unknown fault injector '%s', choose 'molly', 'molly-upstream', 'nemo', 'jepsen', or 'otlp'.", *faultInjFlag)
+	}
+
+	if validate {
+		validateInput(faultInj, faultInjOut)
+		return
 	}
 
 	// Start building structs.
 	debugRun := &DebugRun{
 		workDir:        curDir,
 		allResultsDir:  filepath.Join(curDir, "results"),
-		thisResultsDir: filepath.Join(curDir, "results", filepath.Base(faultInjOut)),
-		faultInj: &fi.Molly{
-			Run:       filepath.Base(faultInjOut),
-			OutputDir: faultInjOut,
-		},
-		graphDB:  &gr.Neo4J{},
-		reporter: &re.Report{},
+		thisResultsDir: filepath.Join(curDir, "results", runName),
+		faultInj:       faultInj,
+		graphDB:        graphDB,
+		reporter:       &re.Report{},
 	}
 
 	// Ensure the results directory for this debug run exists.
@@ -105,7 +351,7 @@ func main() {
 	// Extract, transform, and load fault injector output.
 	err = debugRun.faultInj.LoadOutput()
 	if err != nil {
-		log.Fatalf("Failed to load output from Molly: %v", err)
+		log.Fatalf("Failed to load output from fault injector: %v", err)
 	}
 
 	// Graph queries.
@@ -114,29 +360,56 @@ func main() {
 	iters := debugRun.faultInj.GetRunsIters()
 	failedIters := debugRun.faultInj.GetFailedRunsIters()
 
-	// Connect to graph database docker container.
-	err = debugRun.graphDB.InitGraphDB(graphDBConn, debugRun.faultInj.GetOutput())
+	// Connect to graph database.
+	debugRun.analysisID = analysisName(runName, faultInjOut, debugRun.faultInj.GetFingerprint())
+	err = debugRun.graphDB.InitGraphDB(graphDBConn, debugRun.analysisID, debugRun.faultInj.GetOutput())
 	if err != nil {
 		log.Fatalf("Failed to initialize connection to graph database: %v", err)
 	}
 	defer debugRun.graphDB.CloseDB()
 
-	// Load initial (naive) version of provenance
-	// graphs for antecedent and consequent.
-	err = debugRun.graphDB.LoadRawProvenance()
-	if err != nil {
-		log.Fatalf("Failed to import provenance (naive) into graph database: %v", err)
+	// Reuse provenance imported and simplified
+	// during an earlier run on identical input.
+	fingerprint := debugRun.faultInj.GetFingerprint()
+	cached := false
+	if !*forceReimportFlag {
+
+		cached, err = debugRun.graphDB.RestoreCachedProv(fingerprint)
+		if err != nil {
+			log.Fatalf("Failed to restore cached provenance: %v", err)
+		}
 	}
 
-	// Clean-up loaded provenance data and
-	// re-import in reduced versions.
-	err = debugRun.graphDB.SimplifyProv(iters)
-	if err != nil {
-		log.Fatalf("Could not clean-up initial provenance data: %v", err)
+	if !cached {
+
+		// Load initial (naive) version of provenance
+		// graphs for antecedent and consequent.
+		err = debugRun.graphDB.LoadRawProvenance(debugRun.faultInj)
+		if err != nil {
+			log.Fatalf("Failed to import provenance (naive) into graph database: %v", err)
+		}
+
+		// Clean-up loaded provenance data and
+		// re-import in reduced versions.
+		err = debugRun.graphDB.SimplifyProv(iters)
+		if err != nil {
+			log.Fatalf("Could not clean-up initial provenance data: %v", err)
+		}
+
+		// Remember this input's provenance for next time.
+		err = debugRun.graphDB.CacheProv(fingerprint)
+		if err != nil {
+			log.Fatalf("Failed to cache provenance: %v", err)
+		}
 	}
 
 	// Create hazard analysis DOT figure.
-	hazardDots, err := debugRun.graphDB.CreateHazardAnalysis(faultInjOut)
+	faultInjFS, err := fi.OpenFS(faultInjDir)
+	if err != nil {
+		log.Fatalf("Failed to open fault injector output: %v", err)
+	}
+
+	hazardDots, hazardWindows, err := debugRun.graphDB.CreateHazardAnalysis(faultInjFS)
 	if err != nil {
 		log.Fatalf("Failed to perform hazard analysis of simulation: %v", err)
 	}
@@ -155,18 +428,72 @@ func main() {
 		log.Fatalf("Failed to pull and generate antecedent and consequent provenance DOT: %v", err)
 	}
 
-	// Create differential provenance graphs for
-	// consequent provenance.
-	naiveDiffDots, naiveFailedDots, missingEvents, err := debugRun.graphDB.CreateNaiveDiffProv(false, debugRun.faultInj.GetFailedRunsIters(), postProvDots[0])
+	// Pair each failed run with the most
+	// similar successful run to compare it to.
+	successIters := debugRun.faultInj.GetSuccessRunsIters()
+	pairings, err := debugRun.graphDB.PairRuns(successIters, failedIters)
 	if err != nil {
-		log.Fatalf("Could not create differential provenance between successful and failed provenance: %v", err)
+		log.Fatalf("Could not pair failed with successful runs: %v", err)
+	}
+
+	pairedIters := make([]uint, len(pairings))
+	for i := range pairings {
+		pairedIters[i] = pairings[i].SuccessRun
+	}
+
+	var naiveDiffDots, naiveFailedDots, naiveExcessDots []*gographviz.Graph
+	var missingEvents, excessEvents [][]*fi.Missing
+	var msgDiffs [][]*fi.MessageDiff
+	var alignments []*fi.ProvAlignment
+	var rootCauses [][]*fi.RootCause
+	if pairings != nil {
+
+		// Align the provenance of failed runs with the
+		// one of their successful runs modulo time shifts.
+		alignments, err = debugRun.graphDB.AlignProv(failedIters, pairedIters)
+		if err != nil {
+			log.Fatalf("Could not align provenance of failed and successful runs: %v", err)
+		}
+
+		// Create differential provenance graphs for
+		// consequent provenance.
+		naiveDiffDots, naiveFailedDots, missingEvents, naiveExcessDots, excessEvents, err = debugRun.graphDB.CreateNaiveDiffProv(*symmetricDiffFlag, failedIters, pairedIters, postProvDots)
+		if err != nil {
+			log.Fatalf("Could not create differential provenance between successful and failed provenance: %v", err)
+		}
+
+		// Rank the missing events as root-cause candidates.
+		rootCauses, err = debugRun.graphDB.RankRootCauses(failedIters, missingEvents, *rootCausesFlag)
+		if err != nil {
+			log.Fatalf("Could not rank root-cause candidates: %v", err)
+		}
+
+		// Compare the messages of failed runs against
+		// those of their successful runs.
+		msgDiffs, err = debugRun.graphDB.CreateMessageDiff(pairedIters, failedIters, debugRun.faultInj.GetMsgsFailedRuns())
+		if err != nil {
+			log.Fatalf("Could not compare message flows of failed and successful runs: %v", err)
+		}
+	}
+
+	var suspiciousness []*fi.Suspiciousness
+	if (len(successIters) > 0) && (len(failedIters) > 0) {
+
+		// Score rules and nodes by how strongly
+		// their occurrence correlates with failure.
+		suspiciousness, err = debugRun.graphDB.LocalizeFaults(successIters, failedIters)
+		if err != nil {
+			log.Fatalf("Could not localize faults from rule spectra: %v", err)
+		}
 	}
 
+	refIter := referenceRun(successIters, pairings)
+
 	var corrections []string
 	if len(failedIters) > 0 {
 
 		// Generate correction suggestions for moving towards correctness.
-		corrections, err = debugRun.graphDB.GenerateCorrections()
+		corrections, err = debugRun.graphDB.GenerateCorrections(refIter)
 		if err != nil {
 			log.Fatalf("Error while generating corrections: %v", err)
 		}
@@ -174,7 +501,7 @@ func main() {
 
 	// Attempt to create extension proposals in case
 	// the antecedent depends on network events.
-	allRunsAchievedPre, extensions, err := debugRun.graphDB.GenerateExtensions()
+	allRunsAchievedPre, extensions, err := debugRun.graphDB.GenerateExtensions(refIter)
 	if err != nil {
 		log.Fatalf("Error while generating extensions: %v", err)
 	}
@@ -216,19 +543,39 @@ func main() {
 			runs[iters[i]].Recommendation = append(runs[iters[i]].Recommendation, "Well done! No faults, no missing fault tolerance.")
 		}
 
+		runs[iters[i]].HazardWindows = hazardWindows[i]
 		runs[iters[i]].InterProto = interProto
 		runs[iters[i]].UnionProto = unionProto
+		runs[iters[i]].Suspiciousness = suspiciousness
 	}
 
 	j := 0
 	for i := range failedIters {
 		runs[failedIters[i]].Corrections = corrections
-		runs[failedIters[i]].MissingEvents = missingEvents[j]
+		if pairings != nil {
+			runs[failedIters[i]].Pairing = pairings[j]
+			runs[failedIters[i]].Alignment = alignments[j]
+			runs[failedIters[i]].MissingEvents = missingEvents[j]
+			runs[failedIters[i]].RootCauses = rootCauses[j]
+			runs[failedIters[i]].MessageDiff = msgDiffs[j]
+		}
+		if excessEvents != nil {
+			runs[failedIters[i]].ExcessEvents = excessEvents[j]
+		}
 		runs[failedIters[i]].InterProtoMissing = interProtoMiss[j]
 		runs[failedIters[i]].UnionProtoMissing = unionProtoMiss[j]
 		j++
 	}
 
+	// Group failed runs sharing a failure signature.
+	clusters := gr.ClusterFailures(runs, failedIters)
+	for _, cluster := range clusters {
+
+		for _, member := range cluster.Members {
+			runs[member].FailureCluster = cluster
+		}
+	}
+
 	// Marshal collected debugging information to JSON.
 	debuggingJSON, err := json.Marshal(runs)
 	if err != nil {
@@ -277,16 +624,28 @@ func main() {
 		log.Fatalf("Could not generate cleaned-up consequent provenance figures for report: %v", err)
 	}
 
-	// Generate and write-out naive differential provenance (diff) figures.
-	err = debugRun.reporter.GenerateFigures(failedIters, "diff_post_prov-diff", naiveDiffDots)
-	if err != nil {
-		log.Fatalf("Could not generate naive differential provenance (diff) figures for report: %v", err)
+	if naiveDiffDots != nil {
+
+		// Generate and write-out naive differential provenance (diff) figures.
+		err = debugRun.reporter.GenerateFigures(failedIters, "diff_post_prov-diff", naiveDiffDots)
+		if err != nil {
+			log.Fatalf("Could not generate naive differential provenance (diff) figures for report: %v", err)
+		}
+
+		// Generate and write-out naive differential provenance (failed) figures.
+		err = debugRun.reporter.GenerateFigures(failedIters, "diff_post_prov-failed", naiveFailedDots)
+		if err != nil {
+			log.Fatalf("Could not generate naive differential provenance (failed) figures for report: %v", err)
+		}
 	}
 
-	// Generate and write-out naive differential provenance (failed) figures.
-	err = debugRun.reporter.GenerateFigures(failedIters, "diff_post_prov-failed", naiveFailedDots)
-	if err != nil {
-		log.Fatalf("Could not generate naive differential provenance (failed) figures for report: %v", err)
+	if naiveExcessDots != nil {
+
+		// Generate and write-out reverse naive differential provenance (excess) figures.
+		err = debugRun.reporter.GenerateFigures(failedIters, "diff_post_prov-excess", naiveExcessDots)
+		if err != nil {
+			log.Fatalf("Could not generate reverse naive differential provenance (excess) figures for report: %v", err)
+		}
 	}
 
 	fmt.Printf("All done! Find the debug report here: %s\n\n", filepath.Join(debugRun.thisResultsDir, "index.html"))